@@ -97,6 +97,7 @@ func (a *App) Start() error {
 			&models.Group{},
 			&models.GroupSubGroup{},
 			&models.APIKey{},
+			&models.KeyModelCapability{},
 			&models.RequestLog{},
 			&models.GroupHourlyStat{},
 		); err != nil {
@@ -41,6 +41,17 @@ func (ch *AnthropicChannel) ModifyRequest(req *http.Request, apiKey *models.APIK
 	req.Header.Set("anthropic-version", "2023-06-01")
 }
 
+// GetTranslator serves OpenAI chat completions clients from the Messages API when protocol translation is enabled.
+func (ch *AnthropicChannel) GetTranslator(c *gin.Context, group *models.Group) ProtocolTranslator {
+	if !group.EffectiveConfig.EnableProtocolTranslation || c.Request.Method != http.MethodPost {
+		return nil
+	}
+	if isOpenAIChatPath(c.Request.URL.Path) {
+		return &openAIToAnthropicTranslator{}
+	}
+	return nil
+}
+
 // IsStreamRequest checks if the request is for a streaming response using the pre-read body.
 func (ch *AnthropicChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bool {
 	if strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
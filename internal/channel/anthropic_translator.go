@@ -0,0 +1,478 @@
+package channel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultAnthropicMaxTokens is used when an OpenAI request omits max_tokens, which Anthropic requires.
+const defaultAnthropicMaxTokens = 4096
+
+// openAIToAnthropicTranslator serves OpenAI chat completions clients from the Anthropic Messages API.
+type openAIToAnthropicTranslator struct{}
+
+type anthropicRequest struct {
+	Model         string               `json:"model"`
+	System        []anthropicBlock     `json:"system,omitempty"`
+	Messages      []anthropicMessage   `json:"messages"`
+	MaxTokens     int                  `json:"max_tokens"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	TopP          *float64             `json:"top_p,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	Tools         []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Metadata      *anthropicMetadata   `json:"metadata,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type      string                `json:"type"`
+	Text      string                `json:"text,omitempty"`
+	Source    *anthropicImageSource `json:"source,omitempty"`
+	ID        string                `json:"id,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	Input     any                   `json:"input,omitempty"`
+	ToolUseID string                `json:"tool_use_id,omitempty"`
+	Content   any                   `json:"content,omitempty"`
+	IsError   bool                  `json:"is_error,omitempty"`
+	Thinking  string                `json:"thinking,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type                   string `json:"type"`
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
+type anthropicMetadata struct {
+	UserID string `json:"user_id,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens,omitempty"`
+}
+
+// promptTokens returns all input tokens, including cached ones, as OpenAI reports them.
+func (u anthropicUsage) promptTokens() int64 {
+	return u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Type       string           `json:"type"`
+	Role       string           `json:"role"`
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
+}
+
+// TranslateRequest converts an OpenAI chat completions body into an Anthropic Messages body.
+func (t *openAIToAnthropicTranslator) TranslateRequest(req *http.Request, bodyBytes []byte, isStream bool) ([]byte, error) {
+	var oaReq openAIChatRequest
+	if err := json.Unmarshal(bodyBytes, &oaReq); err != nil {
+		return nil, fmt.Errorf("invalid chat completions request: %w", err)
+	}
+
+	antReq := anthropicRequest{
+		Model:         oaReq.Model,
+		MaxTokens:     defaultAnthropicMaxTokens,
+		Temperature:   oaReq.Temperature,
+		TopP:          oaReq.TopP,
+		StopSequences: openAIStopSequences(oaReq.Stop),
+		Stream:        isStream,
+	}
+	if oaReq.MaxCompletionTokens != nil {
+		antReq.MaxTokens = *oaReq.MaxCompletionTokens
+	} else if oaReq.MaxTokens != nil {
+		antReq.MaxTokens = *oaReq.MaxTokens
+	}
+	if oaReq.User != "" {
+		antReq.Metadata = &anthropicMetadata{UserID: oaReq.User}
+	}
+
+	for _, msg := range oaReq.Messages {
+		switch msg.Role {
+		case "system", "developer":
+			if text := openAIContentText(msg.Content); text != "" {
+				antReq.System = append(antReq.System, anthropicBlock{Type: "text", Text: text})
+			}
+		case "user":
+			antReq.Messages = appendAnthropicMessage(antReq.Messages, "user", openAIPartsToAnthropicBlocks(msg.Content))
+		case "assistant":
+			blocks := openAIPartsToAnthropicBlocks(msg.Content)
+			for _, call := range msg.ToolCalls {
+				blocks = append(blocks, anthropicBlock{
+					Type:  "tool_use",
+					ID:    call.ID,
+					Name:  call.Function.Name,
+					Input: parseToolArguments(call.Function.Arguments),
+				})
+			}
+			antReq.Messages = appendAnthropicMessage(antReq.Messages, "assistant", blocks)
+		case "tool", "function":
+			antReq.Messages = appendAnthropicMessage(antReq.Messages, "user", []anthropicBlock{{
+				Type:      "tool_result",
+				ToolUseID: msg.ToolCallID,
+				Content:   openAIContentText(msg.Content),
+			}})
+		}
+	}
+
+	for _, tool := range oaReq.Tools {
+		if tool.Type != "" && tool.Type != "function" {
+			continue
+		}
+		schema := tool.Function.Parameters
+		if len(schema) == 0 || string(schema) == "null" {
+			schema = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		antReq.Tools = append(antReq.Tools, anthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: schema,
+		})
+	}
+	antReq.ToolChoice = openAIToolChoiceToAnthropic(oaReq.ToolChoice, oaReq.ParallelToolCalls)
+	if len(antReq.Tools) == 0 && antReq.ToolChoice != nil && antReq.ToolChoice.Type != "none" {
+		antReq.ToolChoice = nil
+	}
+
+	req.URL.Path = replacePathSuffix(req.URL.Path, "/chat/completions", "/messages")
+
+	return json.Marshal(antReq)
+}
+
+// appendAnthropicMessage adds content to the conversation, merging consecutive turns of the
+// same role because the Messages API requires user and assistant turns to alternate.
+func appendAnthropicMessage(messages []anthropicMessage, role string, blocks []anthropicBlock) []anthropicMessage {
+	if len(blocks) == 0 {
+		return messages
+	}
+	if n := len(messages); n > 0 && messages[n-1].Role == role {
+		messages[n-1].Content = append(messages[n-1].Content, blocks...)
+		return messages
+	}
+	return append(messages, anthropicMessage{Role: role, Content: blocks})
+}
+
+// openAIPartsToAnthropicBlocks converts OpenAI text and image parts into Anthropic content blocks.
+func openAIPartsToAnthropicBlocks(content any) []anthropicBlock {
+	parts := openAIContentParts(content)
+	blocks := make([]anthropicBlock, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			if part.Text != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: part.Text})
+			}
+		case "image_url":
+			if part.ImageURL == nil || part.ImageURL.URL == "" {
+				continue
+			}
+			source := &anthropicImageSource{Type: "url", URL: part.ImageURL.URL}
+			if mediaType, data, ok := parseDataURL(part.ImageURL.URL); ok {
+				source = &anthropicImageSource{Type: "base64", MediaType: mediaType, Data: data}
+			}
+			blocks = append(blocks, anthropicBlock{Type: "image", Source: source})
+		}
+	}
+	return blocks
+}
+
+// openAIToolChoiceToAnthropic maps the OpenAI tool_choice field onto Anthropic's tool_choice object.
+func openAIToolChoiceToAnthropic(choice any, parallelToolCalls *bool) *anthropicToolChoice {
+	var result *anthropicToolChoice
+	switch v := choice.(type) {
+	case string:
+		switch v {
+		case "auto":
+			result = &anthropicToolChoice{Type: "auto"}
+		case "none":
+			result = &anthropicToolChoice{Type: "none"}
+		case "required":
+			result = &anthropicToolChoice{Type: "any"}
+		}
+	case map[string]any:
+		if fn, ok := v["function"].(map[string]any); ok {
+			if name, ok := fn["name"].(string); ok && name != "" {
+				result = &anthropicToolChoice{Type: "tool", Name: name}
+			}
+		}
+	}
+
+	if parallelToolCalls != nil && !*parallelToolCalls {
+		if result == nil {
+			result = &anthropicToolChoice{Type: "auto"}
+		}
+		if result.Type != "none" {
+			result.DisableParallelToolUse = true
+		}
+	}
+	return result
+}
+
+// anthropicStopReasonToOpenAI maps an Anthropic stop_reason onto an OpenAI finish_reason.
+func anthropicStopReasonToOpenAI(reason string) string {
+	switch reason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
+	case "":
+		return ""
+	default:
+		return "stop"
+	}
+}
+
+// TranslateResponse converts an Anthropic message into an OpenAI chat completion.
+func (t *openAIToAnthropicTranslator) TranslateResponse(bodyBytes []byte) ([]byte, error) {
+	var antResp anthropicResponse
+	if err := json.Unmarshal(bodyBytes, &antResp); err != nil {
+		return nil, fmt.Errorf("invalid messages response: %w", err)
+	}
+
+	var text strings.Builder
+	var toolCalls []openAIToolCall
+	for _, block := range antResp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			arguments, err := json.Marshal(block.Input)
+			if err != nil {
+				arguments = []byte("{}")
+			}
+			toolCalls = append(toolCalls, openAIToolCall{
+				ID:       block.ID,
+				Type:     "function",
+				Function: openAIToolCallFunction{Name: block.Name, Arguments: string(arguments)},
+			})
+		}
+	}
+
+	message := map[string]any{"role": "assistant", "content": nil}
+	if text.Len() > 0 || len(toolCalls) == 0 {
+		message["content"] = text.String()
+	}
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+	}
+
+	prompt := antResp.Usage.promptTokens()
+	result := map[string]any{
+		"id":      "chatcmpl-" + antResp.ID,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   antResp.Model,
+		"choices": []any{map[string]any{
+			"index":         0,
+			"message":       message,
+			"finish_reason": anthropicStopReasonToOpenAI(antResp.StopReason),
+		}},
+		"usage": openAIUsage{
+			PromptTokens:     prompt,
+			CompletionTokens: antResp.Usage.OutputTokens,
+			TotalTokens:      prompt + antResp.Usage.OutputTokens,
+		},
+	}
+	return json.Marshal(result)
+}
+
+// NewStreamTranslator creates a converter from Anthropic stream events to OpenAI chunks.
+func (t *openAIToAnthropicTranslator) NewStreamTranslator() StreamTranslator {
+	return &anthropicToOpenAIStream{
+		created:    time.Now().Unix(),
+		toolIndex:  make(map[int]int),
+		blockTypes: make(map[int]string),
+	}
+}
+
+// anthropicToOpenAIStream holds the state needed to turn Anthropic content blocks into OpenAI deltas.
+type anthropicToOpenAIStream struct {
+	id           string
+	model        string
+	created      int64
+	usage        anthropicUsage
+	finishReason string
+	toolIndex    map[int]int
+	blockTypes   map[int]string
+	finished     bool
+}
+
+type anthropicStreamEvent struct {
+	Type         string             `json:"type"`
+	Index        int                `json:"index"`
+	Message      *anthropicResponse `json:"message,omitempty"`
+	ContentBlock *anthropicBlock    `json:"content_block,omitempty"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta,omitempty"`
+	Usage *anthropicUsage `json:"usage,omitempty"`
+	Error json.RawMessage `json:"error,omitempty"`
+}
+
+// chunk builds an OpenAI chat.completion.chunk frame with a single choice.
+func (s *anthropicToOpenAIStream) chunk(delta map[string]any, finishReason any) ([]byte, error) {
+	return formatSSEData(map[string]any{
+		"id":      "chatcmpl-" + s.id,
+		"object":  "chat.completion.chunk",
+		"created": s.created,
+		"model":   s.model,
+		"choices": []any{map[string]any{
+			"index":         0,
+			"delta":         delta,
+			"finish_reason": finishReason,
+		}},
+	})
+}
+
+// TranslateEvent converts one Anthropic stream event into OpenAI chunks.
+func (s *anthropicToOpenAIStream) TranslateEvent(data []byte) ([]byte, error) {
+	var event anthropicStreamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("invalid stream event: %w", err)
+	}
+
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			s.id = event.Message.ID
+			s.model = event.Message.Model
+			s.usage = event.Message.Usage
+		}
+		return s.chunk(map[string]any{"role": "assistant", "content": ""}, nil)
+
+	case "content_block_start":
+		if event.ContentBlock == nil {
+			return nil, nil
+		}
+		s.blockTypes[event.Index] = event.ContentBlock.Type
+		if event.ContentBlock.Type != "tool_use" {
+			return nil, nil
+		}
+		idx := len(s.toolIndex)
+		s.toolIndex[event.Index] = idx
+		return s.chunk(map[string]any{"tool_calls": []any{map[string]any{
+			"index":    idx,
+			"id":       event.ContentBlock.ID,
+			"type":     "function",
+			"function": map[string]any{"name": event.ContentBlock.Name, "arguments": ""},
+		}}}, nil)
+
+	case "content_block_delta":
+		if event.Delta == nil {
+			return nil, nil
+		}
+		switch event.Delta.Type {
+		case "text_delta":
+			return s.chunk(map[string]any{"content": event.Delta.Text}, nil)
+		case "input_json_delta":
+			idx, ok := s.toolIndex[event.Index]
+			if !ok {
+				return nil, nil
+			}
+			return s.chunk(map[string]any{"tool_calls": []any{map[string]any{
+				"index":    idx,
+				"function": map[string]any{"arguments": event.Delta.PartialJSON},
+			}}}, nil)
+		}
+		return nil, nil
+
+	case "message_delta":
+		if event.Delta != nil && event.Delta.StopReason != "" {
+			s.finishReason = anthropicStopReasonToOpenAI(event.Delta.StopReason)
+		}
+		if event.Usage != nil {
+			s.usage.OutputTokens = event.Usage.OutputTokens
+			if event.Usage.InputTokens > 0 {
+				s.usage.InputTokens = event.Usage.InputTokens
+			}
+		}
+		return nil, nil
+
+	case "message_stop":
+		return s.finish()
+
+	case "error":
+		return formatSSEData(map[string]any{"error": event.Error})
+	}
+
+	return nil, nil
+}
+
+// finish emits the closing chunk carrying the finish reason and the usage totals.
+func (s *anthropicToOpenAIStream) finish() ([]byte, error) {
+	if s.finished {
+		return nil, nil
+	}
+	s.finished = true
+
+	finishReason := s.finishReason
+	if finishReason == "" {
+		finishReason = "stop"
+	}
+	prompt := s.usage.promptTokens()
+	frame, err := formatSSEData(map[string]any{
+		"id":      "chatcmpl-" + s.id,
+		"object":  "chat.completion.chunk",
+		"created": s.created,
+		"model":   s.model,
+		"choices": []any{map[string]any{
+			"index":         0,
+			"delta":         map[string]any{},
+			"finish_reason": finishReason,
+		}},
+		"usage": openAIUsage{
+			PromptTokens:     prompt,
+			CompletionTokens: s.usage.OutputTokens,
+			TotalTokens:      prompt + s.usage.OutputTokens,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(frame, []byte("data: [DONE]\n\n")...), nil
+}
+
+// Finish terminates the OpenAI stream even if the upstream ended without message_stop.
+func (s *anthropicToOpenAIStream) Finish() []byte {
+	if s.finished {
+		return nil
+	}
+	frame, err := s.finish()
+	if err != nil {
+		return []byte("data: [DONE]\n\n")
+	}
+	return frame
+}
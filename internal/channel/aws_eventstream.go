@@ -0,0 +1,194 @@
+package channel
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// maxEventStreamMessageSize bounds a single event-stream message to guard against corrupt framing.
+const maxEventStreamMessageSize = 16 * 1024 * 1024
+
+// eventStreamMessage is a decoded message of the AWS event-stream binary framing.
+type eventStreamMessage struct {
+	Headers map[string]string
+	Payload []byte
+}
+
+// readEventStreamMessage reads one message: a 12-byte prelude (total length, headers length,
+// prelude CRC), the headers, the payload and a trailing message CRC.
+func readEventStreamMessage(r io.Reader) (*eventStreamMessage, error) {
+	prelude := make([]byte, 12)
+	if _, err := io.ReadFull(r, prelude); err != nil {
+		return nil, err
+	}
+	totalLength := binary.BigEndian.Uint32(prelude[0:4])
+	headersLength := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[0:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return nil, fmt.Errorf("event stream prelude checksum mismatch")
+	}
+	if totalLength < 16 || totalLength > maxEventStreamMessageSize || headersLength > totalLength-16 {
+		return nil, fmt.Errorf("invalid event stream message length %d", totalLength)
+	}
+
+	rest := make([]byte, totalLength-12)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, fmt.Errorf("truncated event stream message: %w", err)
+	}
+	crc := crc32.NewIEEE()
+	crc.Write(prelude)
+	crc.Write(rest[:len(rest)-4])
+	if crc.Sum32() != binary.BigEndian.Uint32(rest[len(rest)-4:]) {
+		return nil, fmt.Errorf("event stream message checksum mismatch")
+	}
+
+	headers, err := parseEventStreamHeaders(rest[:headersLength])
+	if err != nil {
+		return nil, err
+	}
+	return &eventStreamMessage{
+		Headers: headers,
+		Payload: rest[headersLength : len(rest)-4],
+	}, nil
+}
+
+// parseEventStreamHeaders decodes the header block. Only string values are kept;
+// other value types are skipped according to their encoded size.
+func parseEventStreamHeaders(data []byte) (map[string]string, error) {
+	headers := make(map[string]string)
+	for len(data) > 0 {
+		nameLength := int(data[0])
+		if len(data) < 1+nameLength+1 {
+			return nil, fmt.Errorf("truncated event stream header")
+		}
+		name := string(data[1 : 1+nameLength])
+		valueType := data[1+nameLength]
+		data = data[2+nameLength:]
+
+		var size int
+		switch valueType {
+		case 0, 1: // bool true / false
+			size = 0
+		case 2: // byte
+			size = 1
+		case 3: // short
+			size = 2
+		case 4: // int
+			size = 4
+		case 5, 8: // long, timestamp
+			size = 8
+		case 9: // uuid
+			size = 16
+		case 6, 7: // byte array, string
+			if len(data) < 2 {
+				return nil, fmt.Errorf("truncated event stream header")
+			}
+			valueLength := int(binary.BigEndian.Uint16(data[:2]))
+			if len(data) < 2+valueLength {
+				return nil, fmt.Errorf("truncated event stream header")
+			}
+			if valueType == 7 {
+				headers[name] = string(data[2 : 2+valueLength])
+			}
+			data = data[2+valueLength:]
+			continue
+		default:
+			return nil, fmt.Errorf("unknown event stream header type %d", valueType)
+		}
+		if len(data) < size {
+			return nil, fmt.Errorf("truncated event stream header")
+		}
+		data = data[size:]
+	}
+	return headers, nil
+}
+
+// eventStreamSSEReader re-frames an AWS event stream as server-sent events.
+type eventStreamSSEReader struct {
+	source io.ReadCloser
+	buf    bytes.Buffer
+	err    error
+}
+
+// newEventStreamSSEReader wraps an event-stream body so it can be consumed as SSE.
+func newEventStreamSSEReader(source io.ReadCloser) io.ReadCloser {
+	return &eventStreamSSEReader{source: source}
+}
+
+func (r *eventStreamSSEReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		msg, err := readEventStreamMessage(r.source)
+		if err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			r.err = err
+			continue
+		}
+		r.buf.Write(eventStreamMessageToSSE(msg))
+	}
+	return r.buf.Read(p)
+}
+
+func (r *eventStreamSSEReader) Close() error {
+	return r.source.Close()
+}
+
+// eventStreamMessageToSSE converts one event-stream message into an SSE frame.
+// InvokeModelWithResponseStream wraps each model chunk as {"bytes":"<base64>"}; the chunk is
+// unwrapped so clients receive the model's native events. Converse events are forwarded as-is.
+func eventStreamMessageToSSE(msg *eventStreamMessage) []byte {
+	if msg.Headers[":message-type"] == "exception" || msg.Headers[":message-type"] == "error" {
+		errorType := msg.Headers[":exception-type"]
+		if errorType == "" {
+			errorType = msg.Headers[":error-code"]
+		}
+		var detail struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(msg.Payload, &detail)
+		if detail.Message == "" {
+			detail.Message = msg.Headers[":error-message"]
+		}
+		frame, _ := formatSSEEvent("error", map[string]any{
+			"type":  "error",
+			"error": map[string]any{"type": errorType, "message": detail.Message},
+		})
+		return frame
+	}
+
+	eventType := msg.Headers[":event-type"]
+	payload := msg.Payload
+	if eventType == "chunk" {
+		var chunk struct {
+			Bytes string `json:"bytes"`
+		}
+		if err := json.Unmarshal(payload, &chunk); err == nil && chunk.Bytes != "" {
+			if decoded, err := base64.StdEncoding.DecodeString(chunk.Bytes); err == nil {
+				payload = decoded
+				var inner struct {
+					Type string `json:"type"`
+				}
+				if json.Unmarshal(decoded, &inner) == nil && inner.Type != "" {
+					eventType = inner.Type
+				}
+			}
+		}
+	}
+
+	var frame bytes.Buffer
+	if eventType != "" {
+		frame.WriteString("event: " + eventType + "\n")
+	}
+	frame.WriteString("data: ")
+	frame.Write(bytes.TrimSpace(payload))
+	frame.WriteString("\n\n")
+	return frame.Bytes()
+}
@@ -0,0 +1,242 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultAzureAPIVersion is used when neither the upstream URL nor the client specifies an api-version.
+	defaultAzureAPIVersion = "2024-10-21"
+	// defaultAzureResponsesAPIVersion is the default for the Responses API, which is only served by preview versions.
+	defaultAzureResponsesAPIVersion = "2025-04-01-preview"
+)
+
+func init() {
+	Register("azure", newAzureChannel)
+}
+
+// AzureChannel proxies OpenAI-style requests to Azure OpenAI deployments.
+// Upstreams are resource endpoints such as https://{resource}.openai.azure.com,
+// optionally carrying the api-version to use, e.g. ?api-version=2024-10-21.
+type AzureChannel struct {
+	*OpenAIChannel
+}
+
+func newAzureChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	base, err := f.newBaseChannel("azure", group)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AzureChannel{
+		OpenAIChannel: &OpenAIChannel{BaseChannel: base},
+	}, nil
+}
+
+// azureAPIVersion returns the api-version configured on the upstream URL, falling back to the default.
+func azureAPIVersion(upstream *url.URL) string {
+	if version := upstream.Query().Get("api-version"); version != "" {
+		return version
+	}
+	return defaultAzureAPIVersion
+}
+
+// isAzureResponsesOperation reports whether an operation path targets the Responses API.
+// Responses are served at resource level, with the deployment passed as the body's model.
+func isAzureResponsesOperation(operation string) bool {
+	return operation == "/responses" || strings.HasPrefix(operation, "/responses/")
+}
+
+// azureOperationPath converts an OpenAI-style request path into the path below /openai.
+// e.g. /v1/chat/completions => /chat/completions
+func azureOperationPath(requestPath string) string {
+	requestPath = strings.TrimPrefix(requestPath, "/v1")
+	if requestPath == "" {
+		return "/"
+	}
+	return requestPath
+}
+
+// BuildUpstreamURL maps the request onto the resource's /openai endpoint and sets the api-version.
+// The deployment segment is inserted later by ApplyModelRedirect, once the model is known.
+func (ch *AzureChannel) BuildUpstreamURL(originalURL *url.URL, groupName string) (string, error) {
+	base := ch.getUpstreamURL()
+	if base == nil {
+		return "", fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	finalURL := *base
+	requestPath := strings.TrimPrefix(originalURL.Path, "/proxy/"+groupName)
+	basePath := strings.TrimSuffix(strings.TrimRight(finalURL.Path, "/"), "/openai")
+
+	// Native Azure paths are forwarded unchanged.
+	if !strings.HasPrefix(requestPath, "/openai/") {
+		requestPath = "/openai" + azureOperationPath(requestPath)
+	}
+	finalURL.Path = basePath + requestPath
+
+	query := originalURL.Query()
+	switch {
+	case base.Query().Get("api-version") != "":
+		query.Set("api-version", azureAPIVersion(base))
+	case query.Get("api-version") != "":
+		// Keep the version requested by the client.
+	case isAzureResponsesOperation(strings.TrimPrefix(requestPath, "/openai")):
+		query.Set("api-version", defaultAzureResponsesAPIVersion)
+	default:
+		query.Set("api-version", defaultAzureAPIVersion)
+	}
+	finalURL.RawQuery = query.Encode()
+
+	return finalURL.String(), nil
+}
+
+// ModifyRequest sets the api-key header used by Azure OpenAI.
+func (ch *AzureChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) {
+	req.Header.Del("Authorization")
+	req.Header.Set("api-key", apiKey.KeyValue)
+}
+
+// resolveDeployment maps a model name to its deployment through the group's redirect rules.
+func resolveDeployment(model string, group *models.Group) (string, error) {
+	if deployment, found := group.ModelRedirectMap[model]; found {
+		return deployment, nil
+	}
+	if group.ModelRedirectStrict {
+		return "", fmt.Errorf("model '%s' is not configured in redirect rules", model)
+	}
+	return model, nil
+}
+
+// ApplyModelRedirect routes the request to the deployment mapped from the requested model.
+// Redirect rules map model names to deployment names; unmapped models are used as deployment names.
+func (ch *AzureChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte, group *models.Group) ([]byte, error) {
+	path := req.URL.Path
+	idx := strings.Index(path, "/openai/")
+	if idx == -1 || strings.Contains(path, "/openai/deployments/") {
+		return bodyBytes, nil
+	}
+	prefix, operation := path[:idx+len("/openai")], path[idx+len("/openai"):]
+
+	// Resource-level endpoints are not bound to a deployment.
+	if strings.HasPrefix(operation, "/models") || strings.HasPrefix(operation, "/files") || strings.HasPrefix(operation, "/batches") {
+		return bodyBytes, nil
+	}
+
+	// The Responses API selects the deployment through the body's model field.
+	if isAzureResponsesOperation(operation) {
+		return ch.BaseChannel.ApplyModelRedirect(req, bodyBytes, group)
+	}
+
+	var model string
+	if contentType := req.Header.Get("Content-Type"); utils.IsMultipartForm(contentType) {
+		model, _ = utils.GetMultipartField(bodyBytes, contentType, "model")
+	} else {
+		var payload struct {
+			Model string `json:"model"`
+		}
+		_ = json.Unmarshal(bodyBytes, &payload)
+		model = payload.Model
+	}
+	if model == "" {
+		return nil, fmt.Errorf("model is required to select an Azure deployment")
+	}
+
+	deployment, err := resolveDeployment(model, group)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.Path = prefix + "/deployments/" + deployment + operation
+	req.URL.RawPath = ""
+
+	logrus.WithFields(logrus.Fields{
+		"group":      group.Name,
+		"model":      model,
+		"deployment": deployment,
+		"channel":    "azure",
+	}).Debug("Model mapped to deployment")
+
+	return bodyBytes, nil
+}
+
+// ValidateKey checks if the given API key is valid by calling the test model's deployment.
+func (ch *AzureChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
+	upstreamURL := ch.getUpstreamURL()
+	if upstreamURL == nil {
+		return false, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	endpointURL, err := url.Parse(ch.ValidationEndpoint)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse validation endpoint: %w", err)
+	}
+
+	deployment, err := resolveDeployment(ch.TestModel, group)
+	if err != nil {
+		return false, err
+	}
+
+	finalURL := *upstreamURL
+	basePath := strings.TrimSuffix(strings.TrimRight(finalURL.Path, "/"), "/openai")
+	finalURL.Path = basePath + "/openai/deployments/" + deployment + azureOperationPath(endpointURL.Path)
+	query := endpointURL.Query()
+	query.Set("api-version", azureAPIVersion(upstreamURL))
+	finalURL.RawQuery = query.Encode()
+
+	payload := gin.H{
+		"messages": []gin.H{
+			{"role": "user", "content": "hi"},
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal validation payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", finalURL.String(), bytes.NewBuffer(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create validation request: %w", err)
+	}
+	req.Header.Set("api-key", apiKey.KeyValue)
+	req.Header.Set("Content-Type", "application/json")
+
+	// Apply custom header rules if available
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := ch.HTTPClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send validation request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Any 2xx status code indicates the key is valid.
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, nil
+	}
+
+	// For non-200 responses, parse the body to provide a more specific error reason.
+	errorBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("key is invalid (status %d), but failed to read error body: %w", resp.StatusCode, err)
+	}
+
+	parsedError := app_errors.ParseUpstreamError(errorBody)
+
+	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+}
@@ -12,7 +12,9 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
 )
@@ -22,6 +24,8 @@ type UpstreamInfo struct {
 	URL           *url.URL
 	Weight        int
 	CurrentWeight int
+	breaker       circuitBreaker
+	stats         upstreamStats
 }
 
 // BaseChannel provides common functionality for channel proxies.
@@ -34,6 +38,14 @@ type BaseChannel struct {
 	ValidationEndpoint string
 	upstreamLock       sync.Mutex
 
+	// Upstream selection, circuit breakers and health probes
+	selectionStrategy   string
+	explorationPercent  int
+	breakerThreshold    int
+	breakerCooldown     time.Duration
+	healthCheckStop     chan struct{}
+	healthCheckStopOnce sync.Once
+
 	// Cached fields from the group for stale check
 	channelType         string
 	groupUpstreams      datatypes.JSON
@@ -42,7 +54,9 @@ type BaseChannel struct {
 	modelRedirectStrict bool
 }
 
-// getUpstreamURL selects an upstream URL using a smooth weighted round-robin algorithm.
+// getUpstreamURL selects an upstream URL using a smooth weighted round-robin algorithm, or by latency
+// with the latency selection strategy. Upstreams with an open circuit breaker are skipped, unless every
+// upstream is open.
 func (b *BaseChannel) getUpstreamURL() *url.URL {
 	b.upstreamLock.Lock()
 	defer b.upstreamLock.Unlock()
@@ -54,11 +68,40 @@ func (b *BaseChannel) getUpstreamURL() *url.URL {
 		return b.Upstreams[0].URL
 	}
 
+	pick := b.pickUpstream
+	if b.selectionStrategy == UpstreamSelectionLatency {
+		pick = b.pickFastestUpstream
+	}
+
+	now := time.Now()
+	best := pick(func(up *UpstreamInfo) bool {
+		return !b.breakersEnabled() || up.breaker.available(now, b.breakerCooldown)
+	})
+	if best == nil {
+		// Failing every request helps nobody: with all upstreams open, keep sharing the load among them.
+		best = pick(func(up *UpstreamInfo) bool { return true })
+	}
+	if best == nil {
+		return b.Upstreams[0].URL // 降级到第一个可用的
+	}
+
+	if b.breakersEnabled() {
+		best.breaker.picked(now)
+	}
+	return best.URL
+}
+
+// pickUpstream runs one round of smooth weighted round robin over the eligible upstreams.
+// The caller holds upstreamLock.
+func (b *BaseChannel) pickUpstream(eligible func(up *UpstreamInfo) bool) *UpstreamInfo {
 	totalWeight := 0
 	var best *UpstreamInfo
 
 	for i := range b.Upstreams {
 		up := &b.Upstreams[i]
+		if !eligible(up) {
+			continue
+		}
 		totalWeight += up.Weight
 		up.CurrentWeight += up.Weight
 
@@ -67,12 +110,10 @@ func (b *BaseChannel) getUpstreamURL() *url.URL {
 		}
 	}
 
-	if best == nil {
-		return b.Upstreams[0].URL // 降级到第一个可用的
+	if best != nil {
+		best.CurrentWeight -= totalWeight
 	}
-
-	best.CurrentWeight -= totalWeight
-	return best.URL
+	return best
 }
 
 // BuildUpstreamURL constructs the target URL for the upstream service.
@@ -146,12 +187,24 @@ func (b *BaseChannel) GetStreamClient() *http.Client {
 	return b.StreamClient
 }
 
+// DecodeStreamResponse leaves the response untouched by default, as most upstreams already stream SSE.
+func (b *BaseChannel) DecodeStreamResponse(resp *http.Response) {}
+
+// GetTranslator returns nil by default: requests are forwarded in the client's own format.
+func (b *BaseChannel) GetTranslator(c *gin.Context, group *models.Group) ProtocolTranslator {
+	return nil
+}
+
 // ApplyModelRedirect applies model redirection based on the group's redirect rules.
 func (b *BaseChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte, group *models.Group) ([]byte, error) {
 	if len(group.ModelRedirectMap) == 0 || len(bodyBytes) == 0 {
 		return bodyBytes, nil
 	}
 
+	if contentType := req.Header.Get("Content-Type"); utils.IsMultipartForm(contentType) {
+		return applyMultipartModelRedirect(bodyBytes, contentType, "model", group)
+	}
+
 	var requestData map[string]any
 	if err := json.Unmarshal(bodyBytes, &requestData); err != nil {
 		return bodyBytes, nil
@@ -189,6 +242,36 @@ func (b *BaseChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte, gr
 	return bodyBytes, nil
 }
 
+// applyMultipartModelRedirect applies the redirect rules to the model field of a multipart/form-data body.
+func applyMultipartModelRedirect(bodyBytes []byte, contentType, field string, group *models.Group) ([]byte, error) {
+	model, ok := utils.GetMultipartField(bodyBytes, contentType, field)
+	if !ok {
+		return bodyBytes, nil
+	}
+
+	if targetModel, found := group.ModelRedirectMap[model]; found {
+		rewritten, err := utils.SetMultipartFields(bodyBytes, contentType, map[string]string{field: targetModel})
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewrite model in multipart body: %w", err)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"group":          group.Name,
+			"original_model": model,
+			"target_model":   targetModel,
+			"channel":        "multipart_form",
+		}).Debug("Model redirected")
+
+		return rewritten, nil
+	}
+
+	if group.ModelRedirectStrict {
+		return nil, fmt.Errorf("model '%s' is not configured in redirect rules", model)
+	}
+
+	return bodyBytes, nil
+}
+
 // TransformModelList transforms the model list response based on redirect rules.
 func (b *BaseChannel) TransformModelList(req *http.Request, bodyBytes []byte, group *models.Group) (map[string]any, error) {
 	var response map[string]any
@@ -0,0 +1,224 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	bedrockService       = "bedrock"
+	defaultBedrockRegion = "us-east-1"
+)
+
+func init() {
+	Register("bedrock", newBedrockChannel)
+}
+
+// BedrockChannel proxies the Bedrock runtime API (invoke and converse), signing each request with SigV4.
+// Keys are credential pairs in the form ACCESS_KEY_ID:SECRET_ACCESS_KEY[:SESSION_TOKEN].
+// The region is taken from the upstream host (bedrock-runtime.{region}.amazonaws.com),
+// or from a "region" query parameter on the upstream URL for other hosts.
+type BedrockChannel struct {
+	*BaseChannel
+	regions map[string]string
+}
+
+func newBedrockChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	base, err := f.newBaseChannel("bedrock", group)
+	if err != nil {
+		return nil, err
+	}
+
+	regions := make(map[string]string, len(base.Upstreams))
+	for _, up := range base.Upstreams {
+		regions[up.URL.Host] = bedrockRegion(up.URL)
+	}
+
+	return &BedrockChannel{
+		BaseChannel: base,
+		regions:     regions,
+	}, nil
+}
+
+// bedrockRegion determines the signing region of an upstream URL.
+func bedrockRegion(u *url.URL) string {
+	if region := u.Query().Get("region"); region != "" {
+		return region
+	}
+	// e.g. bedrock-runtime.us-west-2.amazonaws.com or bedrock-runtime-fips.us-east-1.amazonaws.com
+	labels := strings.Split(u.Hostname(), ".")
+	if len(labels) >= 4 && strings.HasPrefix(labels[0], "bedrock") && labels[len(labels)-2] == "amazonaws" {
+		return labels[1]
+	}
+	return defaultBedrockRegion
+}
+
+// regionFor returns the signing region for the host a request is sent to.
+func (ch *BedrockChannel) regionFor(u *url.URL) string {
+	if region, ok := ch.regions[u.Host]; ok {
+		return region
+	}
+	return bedrockRegion(u)
+}
+
+// ModifyRequest signs the request with the key's AWS credentials.
+func (ch *BedrockChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) {
+	creds, err := parseAWSCredentials(apiKey.KeyValue)
+	if err != nil {
+		logrus.WithField("group", group.Name).Warnf("Bedrock key %s: %v", utils.MaskAPIKey(apiKey.KeyValue), err)
+		return
+	}
+	if err := signAWSRequest(req, creds, ch.regionFor(req.URL), bedrockService, time.Now()); err != nil {
+		logrus.WithField("group", group.Name).Errorf("Failed to sign Bedrock request: %v", err)
+	}
+}
+
+// bedrockModelSegment returns the index of the model ID segment in a /model/{modelId}/... path.
+func bedrockModelSegment(parts []string) int {
+	for i, part := range parts {
+		if part == "model" && i+1 < len(parts) {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// IsStreamRequest checks for the streaming invoke and converse operations.
+func (ch *BedrockChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bool {
+	path := strings.TrimRight(c.Request.URL.Path, "/")
+	return strings.HasSuffix(path, "/invoke-with-response-stream") || strings.HasSuffix(path, "/converse-stream")
+}
+
+// ExtractModel extracts the model ID from the request path.
+func (ch *BedrockChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
+	parts := strings.Split(c.Request.URL.Path, "/")
+	if idx := bedrockModelSegment(parts); idx != -1 {
+		if model, err := url.PathUnescape(parts[idx]); err == nil {
+			return model
+		}
+		return parts[idx]
+	}
+	return ""
+}
+
+// ApplyModelRedirect rewrites the model ID in the request path.
+func (ch *BedrockChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte, group *models.Group) ([]byte, error) {
+	if len(group.ModelRedirectMap) == 0 {
+		return bodyBytes, nil
+	}
+
+	parts := strings.Split(req.URL.Path, "/")
+	idx := bedrockModelSegment(parts)
+	if idx == -1 {
+		return bodyBytes, nil
+	}
+
+	originalModel := parts[idx]
+	if targetModel, found := group.ModelRedirectMap[originalModel]; found {
+		parts[idx] = targetModel
+		req.URL.Path = strings.Join(parts, "/")
+		req.URL.RawPath = ""
+
+		logrus.WithFields(logrus.Fields{
+			"group":          group.Name,
+			"original_model": originalModel,
+			"target_model":   targetModel,
+			"channel":        "bedrock",
+		}).Debug("Model redirected")
+		return bodyBytes, nil
+	}
+
+	if group.ModelRedirectStrict {
+		return nil, fmt.Errorf("model '%s' is not configured in redirect rules", originalModel)
+	}
+	return bodyBytes, nil
+}
+
+// DecodeStreamResponse re-frames Bedrock's binary event stream as SSE.
+func (ch *BedrockChannel) DecodeStreamResponse(resp *http.Response) {
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/vnd.amazon.eventstream") {
+		return
+	}
+	resp.Body = newEventStreamSSEReader(resp.Body)
+	resp.Header.Set("Content-Type", "text/event-stream")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+}
+
+// ValidateKey checks if the given credentials are valid by making a converse request.
+func (ch *BedrockChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
+	upstreamURL := ch.getUpstreamURL()
+	if upstreamURL == nil {
+		return false, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	creds, err := parseAWSCredentials(apiKey.KeyValue)
+	if err != nil {
+		return false, err
+	}
+
+	finalURL := *upstreamURL
+	finalURL.RawQuery = ""
+	finalURL.Path = strings.TrimRight(finalURL.Path, "/") + "/model/" + ch.TestModel + "/converse"
+
+	payload := gin.H{
+		"messages": []gin.H{
+			{"role": "user", "content": []gin.H{{"text": "hi"}}},
+		},
+		"inferenceConfig": gin.H{"maxTokens": 10},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal validation payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", finalURL.String(), bytes.NewBuffer(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create validation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Apply custom header rules if available
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	if err := signAWSRequest(req, creds, bedrockRegion(upstreamURL), bedrockService, time.Now()); err != nil {
+		return false, err
+	}
+
+	resp, err := ch.HTTPClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send validation request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Any 2xx status code indicates the key is valid.
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, nil
+	}
+
+	// For non-200 responses, parse the body to provide a more specific error reason.
+	errorBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("key is invalid (status %d), but failed to read error body: %w", resp.StatusCode, err)
+	}
+
+	parsedError := app_errors.ParseUpstreamError(errorBody)
+
+	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+}
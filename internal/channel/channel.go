@@ -5,6 +5,7 @@ import (
 	"gpt-load/internal/models"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -40,4 +41,26 @@ type ChannelProxy interface {
 
 	// TransformModelList transforms the model list response based on redirect rules.
 	TransformModelList(req *http.Request, bodyBytes []byte, group *models.Group) (map[string]any, error)
+
+	// DecodeStreamResponse converts a streaming response that is not SSE into SSE in place.
+	DecodeStreamResponse(resp *http.Response)
+
+	// GetTranslator returns the protocol translator for the request, or nil when it is forwarded as-is.
+	GetTranslator(c *gin.Context, group *models.Group) ProtocolTranslator
+
+	// ReportUpstreamResult feeds the outcome and time to response headers of a request to its upstream's
+	// stats and circuit breaker. failed marks a connection error or a 5xx response. It reports whether a
+	// breaker tracks the upstream.
+	ReportUpstreamResult(upstreamURL string, failed bool, latency time.Duration) bool
+}
+
+// RequestAuthorizer is implemented by channels that exchange the key for credentials sent with each request,
+// such as the access tokens of Vertex AI service accounts.
+type RequestAuthorizer interface {
+	// AuthorizeRequest adds the key's credentials to the request. An error means they could not be obtained,
+	// e.g. because the token endpoint is down, which is not the key's fault.
+	AuthorizeRequest(req *http.Request, apiKey *models.APIKey) error
+
+	// RejectAuthorization drops the cached credentials of the key after the upstream refused them.
+	RejectAuthorization(req *http.Request, apiKey *models.APIKey)
 }
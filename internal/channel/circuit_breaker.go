@@ -0,0 +1,223 @@
+package channel
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// healthCheckTimeout bounds a single active health probe.
+const healthCheckTimeout = 10 * time.Second
+
+// Circuit breaker states of an upstream.
+const (
+	breakerClosed   = iota // Requests flow normally
+	breakerOpen            // The upstream is skipped until the cooldown ends
+	breakerHalfOpen        // A single trial request decides whether the upstream is back
+)
+
+var breakerStateNames = map[int]string{
+	breakerClosed:   "closed",
+	breakerOpen:     "open",
+	breakerHalfOpen: "half-open",
+}
+
+// circuitBreaker tracks the health of one upstream. It is guarded by the channel's upstreamLock.
+type circuitBreaker struct {
+	state    int
+	failures int       // Consecutive upstream failures
+	since    time.Time // When the breaker opened, or when the half-open trial was let through
+}
+
+// available reports whether requests may be sent to the upstream. Once the cooldown of an open breaker
+// has passed, the upstream is available for a trial. A trial whose outcome is never reported holds the
+// upstream for another cooldown only.
+func (cb *circuitBreaker) available(now time.Time, cooldown time.Duration) bool {
+	return cb.state == breakerClosed || now.Sub(cb.since) >= cooldown
+}
+
+// picked records that a request was sent to the upstream; for an open breaker it is the trial.
+func (cb *circuitBreaker) picked(now time.Time) {
+	if cb.state != breakerClosed {
+		cb.state = breakerHalfOpen
+		cb.since = now
+	}
+}
+
+// record feeds the outcome of a request to the breaker. Any answer from the upstream closes it; the
+// threshold of consecutive failures, or a failed trial, opens it.
+func (cb *circuitBreaker) record(failed bool, threshold int, now time.Time) {
+	if !failed {
+		cb.state = breakerClosed
+		cb.failures = 0
+		return
+	}
+	cb.failures++
+	if cb.state == breakerHalfOpen || (cb.state == breakerClosed && cb.failures >= threshold) {
+		cb.state = breakerOpen
+		cb.since = now
+	}
+}
+
+// breakersEnabled reports whether the channel tracks the health of its upstreams. A single upstream
+// cannot be skipped, so its failures are left to the keys as before.
+func (b *BaseChannel) breakersEnabled() bool {
+	return b.breakerThreshold > 0 && len(b.Upstreams) > 1
+}
+
+// ReportUpstreamResult feeds the outcome and latency of a request to the stats and the circuit breaker of
+// the upstream it was sent to. It reports whether a breaker tracks the upstream, in which case its failures
+// are not the key's fault.
+func (b *BaseChannel) ReportUpstreamResult(upstreamURL string, failed bool, latency time.Duration) bool {
+	if len(b.Upstreams) < 2 {
+		return false
+	}
+	target, err := url.Parse(upstreamURL)
+	if err != nil {
+		return false
+	}
+
+	b.upstreamLock.Lock()
+	defer b.upstreamLock.Unlock()
+	up := b.upstreamFor(target)
+	if up == nil {
+		return false
+	}
+	up.stats.record(failed, latency)
+	if !b.breakersEnabled() {
+		return false
+	}
+	b.recordUpstreamResult(up, failed)
+	return true
+}
+
+// recordUpstreamResult updates the breaker of the upstream, logging state changes. The caller holds upstreamLock.
+func (b *BaseChannel) recordUpstreamResult(up *UpstreamInfo, failed bool) {
+	from := up.breaker.state
+	up.breaker.record(failed, b.breakerThreshold, time.Now())
+	if to := up.breaker.state; to != from {
+		entry := logrus.WithFields(logrus.Fields{
+			"channel":  b.Name,
+			"upstream": up.URL.String(),
+			"from":     breakerStateNames[from],
+			"to":       breakerStateNames[to],
+		})
+		if to == breakerOpen {
+			entry.WithField("failures", up.breaker.failures).Warn("Upstream circuit breaker opened")
+		} else {
+			entry.Info("Upstream circuit breaker changed state")
+		}
+	}
+}
+
+// upstreamFor finds the upstream a request URL was built from: the one on the same scheme and host with
+// the longest base path the URL starts with. Translated requests may leave the base path, in which case
+// the first upstream on that host is used. The caller holds upstreamLock.
+func (b *BaseChannel) upstreamFor(target *url.URL) *UpstreamInfo {
+	var best *UpstreamInfo
+	bestLen := -1
+	for i := range b.Upstreams {
+		up := &b.Upstreams[i]
+		if up.URL.Scheme != target.Scheme || up.URL.Host != target.Host {
+			continue
+		}
+		matchLen := 0
+		if basePath := strings.TrimRight(up.URL.Path, "/"); strings.HasPrefix(target.Path, basePath) {
+			matchLen = len(basePath) + 1
+		}
+		if matchLen > bestLen {
+			best, bestLen = up, matchLen
+		}
+	}
+	return best
+}
+
+// upstreamHealthReporter is implemented by channels that know whether their upstreams can take requests.
+type upstreamHealthReporter interface {
+	hasAvailableUpstream() bool
+}
+
+// hasAvailableUpstream reports whether the circuit breaker of any upstream lets requests through.
+func (b *BaseChannel) hasAvailableUpstream() bool {
+	if !b.breakersEnabled() {
+		return true
+	}
+
+	b.upstreamLock.Lock()
+	defer b.upstreamLock.Unlock()
+	now := time.Now()
+	for i := range b.Upstreams {
+		if b.Upstreams[i].breaker.available(now, b.breakerCooldown) {
+			return true
+		}
+	}
+	return false
+}
+
+// upstreamHealthChecker is implemented by channels that probe their upstreams in the background.
+type upstreamHealthChecker interface {
+	startHealthChecks(interval time.Duration)
+	stopHealthChecks()
+}
+
+// startHealthChecks starts probing every upstream at the interval, if probes are enabled.
+func (b *BaseChannel) startHealthChecks(interval time.Duration) {
+	if interval <= 0 || !b.breakersEnabled() {
+		return
+	}
+	go b.runHealthChecks(interval)
+}
+
+// runHealthChecks probes every upstream at the interval until the channel is replaced.
+func (b *BaseChannel) runHealthChecks(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.healthCheckStop:
+			return
+		case <-ticker.C:
+			for i := range b.Upstreams {
+				b.probeUpstream(i)
+			}
+		}
+	}
+}
+
+// probeUpstream sends a GET to the upstream's base URL. Any response below 500 counts as healthy:
+// most API bases answer 401 or 404 without a key, which is enough to know the upstream is up.
+func (b *BaseChannel) probeUpstream(index int) {
+	b.upstreamLock.Lock()
+	target := b.Upstreams[index].URL.String()
+	b.upstreamLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	failed := true
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err == nil {
+		var resp *http.Response
+		resp, err = b.HTTPClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			failed = resp.StatusCode >= http.StatusInternalServerError
+		}
+	}
+	if failed {
+		logrus.WithFields(logrus.Fields{"channel": b.Name, "upstream": target, "error": err}).Debug("Upstream health probe failed")
+	}
+
+	b.upstreamLock.Lock()
+	defer b.upstreamLock.Unlock()
+	b.recordUpstreamResult(&b.Upstreams[index], failed)
+}
+
+// stopHealthChecks ends the active health probes of a channel that is being replaced.
+func (b *BaseChannel) stopHealthChecks() {
+	b.healthCheckStopOnce.Do(func() { close(b.healthCheckStop) })
+}
@@ -0,0 +1,337 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+)
+
+const (
+	customAuthHeader = "header"
+	customAuthQuery  = "query"
+	customAuthBasic  = "basic"
+
+	defaultCustomValidationPath = "/v1/chat/completions"
+	defaultCustomValidationBody = `{"model":"{{model}}","messages":[{"role":"user","content":"hi"}]}`
+)
+
+func init() {
+	Register("custom", newCustomChannel)
+}
+
+// CustomChannel proxies OpenAI-like vendors whose behaviour is described by the group's channel_config
+// instead of code: where the key goes, which JSON path holds the model, how streaming is detected,
+// and the request used to validate keys.
+type CustomChannel struct {
+	*BaseChannel
+	config        *models.CustomChannelConfig
+	channelConfig datatypes.JSONMap
+}
+
+func newCustomChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	config, err := ParseCustomChannelConfig(group.ChannelConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid channel config for group %s: %w", group.Name, err)
+	}
+
+	base, err := f.newBaseChannel("custom", group)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CustomChannel{
+		BaseChannel:   base,
+		config:        config,
+		channelConfig: group.ChannelConfig,
+	}, nil
+}
+
+// ParseCustomChannelConfig decodes a group's channel_config, fills in defaults and validates it.
+func ParseCustomChannelConfig(raw datatypes.JSONMap) (*models.CustomChannelConfig, error) {
+	config := &models.CustomChannelConfig{}
+	if len(raw) > 0 {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("malformed channel config: %w", err)
+		}
+	}
+
+	auth := &config.Auth
+	auth.Type = strings.ToLower(strings.TrimSpace(auth.Type))
+	switch auth.Type {
+	case "", customAuthHeader:
+		auth.Type = customAuthHeader
+		if strings.TrimSpace(auth.Header) == "" {
+			auth.Header = "Authorization"
+			if auth.Prefix == "" {
+				auth.Prefix = "Bearer "
+			}
+		}
+	case customAuthQuery:
+		if strings.TrimSpace(auth.Query) == "" {
+			return nil, fmt.Errorf("auth.query is required for query auth")
+		}
+	case customAuthBasic:
+	default:
+		return nil, fmt.Errorf("unsupported auth.type '%s', must be header, query or basic", auth.Type)
+	}
+
+	if config.ModelPath == "" {
+		config.ModelPath = "model"
+	}
+	if config.Stream.BodyPath == "" {
+		config.Stream.BodyPath = "stream"
+	}
+
+	validation := &config.Validation
+	if validation.Method == "" {
+		validation.Method = http.MethodPost
+	}
+	validation.Method = strings.ToUpper(validation.Method)
+	if validation.Path != "" && !strings.HasPrefix(validation.Path, "/") {
+		return nil, fmt.Errorf("validation.path must start with /")
+	}
+	if validation.Body == "" && validation.Method == http.MethodPost {
+		validation.Body = defaultCustomValidationBody
+	}
+
+	return config, nil
+}
+
+// IsConfigStale also rebuilds the channel when its channel config changes.
+func (ch *CustomChannel) IsConfigStale(group *models.Group) bool {
+	if ch.BaseChannel.IsConfigStale(group) {
+		return true
+	}
+	return !reflect.DeepEqual(ch.channelConfig, group.ChannelConfig)
+}
+
+// ModifyRequest places the key where the channel config declares.
+func (ch *CustomChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) {
+	applyCustomAuth(req, &ch.config.Auth, apiKey.KeyValue)
+}
+
+// applyCustomAuth sets the key on the request as a header, a query parameter or basic auth credentials.
+func applyCustomAuth(req *http.Request, auth *models.CustomAuthConfig, keyValue string) {
+	switch auth.Type {
+	case customAuthQuery:
+		q := req.URL.Query()
+		q.Set(auth.Query, keyValue)
+		req.URL.RawQuery = q.Encode()
+	case customAuthBasic:
+		username, password := auth.Username, keyValue
+		if username == "" {
+			username, password, _ = strings.Cut(keyValue, ":")
+		}
+		req.SetBasicAuth(username, password)
+	default:
+		if !strings.EqualFold(auth.Header, "Authorization") {
+			req.Header.Del("Authorization")
+		}
+		req.Header.Set(auth.Header, auth.Prefix+keyValue)
+	}
+}
+
+// IsStreamRequest checks the configured path suffix, query condition and body flag.
+func (ch *CustomChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bool {
+	stream := ch.config.Stream
+	if stream.PathSuffix != "" && strings.HasSuffix(c.Request.URL.Path, stream.PathSuffix) {
+		return true
+	}
+	if stream.Query != "" {
+		name, value, hasValue := strings.Cut(stream.Query, "=")
+		if query := c.Request.URL.Query(); query.Has(name) && (!hasValue || query.Get(name) == value) {
+			return true
+		}
+	}
+	if strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		return true
+	}
+
+	var payload any
+	if err := json.Unmarshal(bodyBytes, &payload); err != nil {
+		return false
+	}
+	flag, _ := jsonPathGet(payload, stream.BodyPath).(bool)
+	return flag
+}
+
+// ExtractModel reads the model from the configured JSON path.
+func (ch *CustomChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
+	if contentType := c.GetHeader("Content-Type"); utils.IsMultipartForm(contentType) {
+		model, _ := utils.GetMultipartField(bodyBytes, contentType, ch.config.ModelPath)
+		return model
+	}
+
+	var payload any
+	if err := json.Unmarshal(bodyBytes, &payload); err != nil {
+		return ""
+	}
+	model, _ := jsonPathGet(payload, ch.config.ModelPath).(string)
+	return model
+}
+
+// ApplyModelRedirect rewrites the model at the configured JSON path.
+func (ch *CustomChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte, group *models.Group) ([]byte, error) {
+	if len(group.ModelRedirectMap) == 0 || len(bodyBytes) == 0 {
+		return bodyBytes, nil
+	}
+
+	// For multipart bodies the model path names the form field.
+	if contentType := req.Header.Get("Content-Type"); utils.IsMultipartForm(contentType) {
+		return applyMultipartModelRedirect(bodyBytes, contentType, ch.config.ModelPath, group)
+	}
+
+	var payload any
+	if err := json.Unmarshal(bodyBytes, &payload); err != nil {
+		return bodyBytes, nil
+	}
+	model, ok := jsonPathGet(payload, ch.config.ModelPath).(string)
+	if !ok {
+		return bodyBytes, nil
+	}
+
+	if targetModel, found := group.ModelRedirectMap[model]; found {
+		jsonPathSet(payload, ch.config.ModelPath, targetModel)
+
+		logrus.WithFields(logrus.Fields{
+			"group":          group.Name,
+			"original_model": model,
+			"target_model":   targetModel,
+			"channel":        "custom",
+		}).Debug("Model redirected")
+
+		return json.Marshal(payload)
+	}
+
+	if group.ModelRedirectStrict {
+		return nil, fmt.Errorf("model '%s' is not configured in redirect rules", model)
+	}
+	return bodyBytes, nil
+}
+
+// jsonPathGet resolves a dot-separated path of object keys, returning nil when any segment is missing.
+func jsonPathGet(data any, path string) any {
+	for _, segment := range strings.Split(path, ".") {
+		obj, ok := data.(map[string]any)
+		if !ok {
+			return nil
+		}
+		data = obj[segment]
+	}
+	return data
+}
+
+// jsonPathSet assigns the value at a dot-separated path whose parent objects already exist.
+func jsonPathSet(data any, path string, value any) {
+	segments := strings.Split(path, ".")
+	for _, segment := range segments[:len(segments)-1] {
+		obj, ok := data.(map[string]any)
+		if !ok {
+			return
+		}
+		data = obj[segment]
+	}
+	if obj, ok := data.(map[string]any); ok {
+		obj[segments[len(segments)-1]] = value
+	}
+}
+
+// ValidateKey sends the configured validation request with the test model.
+func (ch *CustomChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
+	upstreamURL := ch.getUpstreamURL()
+	if upstreamURL == nil {
+		return false, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	validation := ch.config.Validation
+	endpoint := ch.ValidationEndpoint
+	if endpoint == "" {
+		endpoint = validation.Path
+	}
+	if endpoint == "" {
+		endpoint = defaultCustomValidationPath
+	}
+	endpoint = strings.ReplaceAll(endpoint, "{{model}}", url.PathEscape(ch.TestModel))
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse validation endpoint: %w", err)
+	}
+
+	finalURL := *upstreamURL
+	endpointPath := endpointURL.Path
+	// Compatibility: avoid duplicated API version prefix when the upstream base URL already includes it.
+	basePath := strings.TrimRight(finalURL.Path, "/")
+	if strings.HasSuffix(basePath, "/v1") && strings.HasPrefix(endpointPath, "/v1") {
+		endpointPath = strings.TrimPrefix(endpointPath, "/v1")
+	}
+	finalURL.Path = basePath + endpointPath
+	finalURL.RawQuery = endpointURL.RawQuery
+
+	var body io.Reader
+	if validation.Body != "" {
+		// JSON-escape the model so it stays valid inside the template's quotes.
+		escaped, err := json.Marshal(ch.TestModel)
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal validation payload: %w", err)
+		}
+		model := string(escaped[1 : len(escaped)-1])
+		body = bytes.NewBufferString(strings.ReplaceAll(validation.Body, "{{model}}", model))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, validation.Method, finalURL.String(), body)
+	if err != nil {
+		return false, fmt.Errorf("failed to create validation request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range validation.Headers {
+		req.Header.Set(name, value)
+	}
+	applyCustomAuth(req, &ch.config.Auth, apiKey.KeyValue)
+
+	// Apply custom header rules if available
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := ch.HTTPClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send validation request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Any 2xx status code indicates the key is valid.
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, nil
+	}
+
+	// For non-200 responses, parse the body to provide a more specific error reason.
+	errorBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("key is invalid (status %d), but failed to read error body: %w", resp.StatusCode, err)
+	}
+
+	parsedError := app_errors.ParseUpstreamError(errorBody)
+
+	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+}
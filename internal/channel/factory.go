@@ -65,6 +65,9 @@ func (f *Factory) GetChannel(group *models.Group) (ChannelProxy, error) {
 		if !channel.IsConfigStale(group) {
 			return channel, nil
 		}
+		if checker, ok := channel.(upstreamHealthChecker); ok {
+			checker.stopHealthChecks()
+		}
 	}
 
 	logrus.Debugf("Creating new channel for group %d with type '%s'", group.ID, group.ChannelType)
@@ -77,10 +80,47 @@ func (f *Factory) GetChannel(group *models.Group) (ChannelProxy, error) {
 	if err != nil {
 		return nil, err
 	}
+	if checker, ok := channel.(upstreamHealthChecker); ok {
+		checker.startHealthChecks(time.Duration(group.EffectiveConfig.UpstreamHealthCheckInterval) * time.Second)
+	}
 	f.channelCache[group.ID] = channel
 	return channel, nil
 }
 
+// PruneChannels drops the cached channels of groups that no longer exist, stopping their health probes.
+func (f *Factory) PruneChannels(groups map[string]*models.Group) {
+	live := make(map[uint]bool, len(groups))
+	for _, group := range groups {
+		live[group.ID] = true
+	}
+
+	f.cacheLock.Lock()
+	defer f.cacheLock.Unlock()
+	for groupID, channel := range f.channelCache {
+		if live[groupID] {
+			continue
+		}
+		if checker, ok := channel.(upstreamHealthChecker); ok {
+			checker.stopHealthChecks()
+		}
+		delete(f.channelCache, groupID)
+		logrus.Debugf("Removed cached channel of deleted group %d", groupID)
+	}
+}
+
+// HasHealthyUpstream reports whether the channel of the group has an upstream that can take requests.
+// A group whose channel was not created yet has not failed any request and counts as healthy.
+func (f *Factory) HasHealthyUpstream(groupID uint) bool {
+	f.cacheLock.Lock()
+	channel, ok := f.channelCache[groupID]
+	f.cacheLock.Unlock()
+	if !ok {
+		return true
+	}
+	reporter, ok := channel.(upstreamHealthReporter)
+	return !ok || reporter.hasAvailableUpstream()
+}
+
 // newBaseChannel is a helper function to create and configure a BaseChannel.
 func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel, error) {
 	type upstreamDef struct {
@@ -147,6 +187,11 @@ func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel
 		StreamClient:        streamClient,
 		TestModel:           group.TestModel,
 		ValidationEndpoint:  utils.GetValidationEndpoint(group),
+		selectionStrategy:   group.EffectiveConfig.UpstreamSelectionStrategy,
+		explorationPercent:  group.EffectiveConfig.UpstreamExplorationPercent,
+		breakerThreshold:    group.EffectiveConfig.CircuitBreakerThreshold,
+		breakerCooldown:     time.Duration(group.EffectiveConfig.CircuitBreakerCooldown) * time.Second,
+		healthCheckStop:     make(chan struct{}),
 		channelType:         group.ChannelType,
 		groupUpstreams:      group.Upstreams,
 		effectiveConfig:     &group.EffectiveConfig,
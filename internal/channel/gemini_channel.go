@@ -47,6 +47,19 @@ func (ch *GeminiChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey,
 	}
 }
 
+// GetTranslator serves OpenAI chat completions clients from the native generateContent API
+// when protocol translation is enabled. The v1beta/openai compatibility endpoint is left untouched.
+func (ch *GeminiChannel) GetTranslator(c *gin.Context, group *models.Group) ProtocolTranslator {
+	if !group.EffectiveConfig.EnableProtocolTranslation || c.Request.Method != http.MethodPost {
+		return nil
+	}
+	path := c.Request.URL.Path
+	if isOpenAIChatPath(path) && !strings.Contains(path, "v1beta/openai") {
+		return &openAIToGeminiTranslator{}
+	}
+	return nil
+}
+
 // IsStreamRequest checks if the request is for a streaming response.
 func (ch *GeminiChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bool {
 	path := c.Request.URL.Path
@@ -172,7 +185,7 @@ func (ch *GeminiChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte,
 		return bodyBytes, nil
 	}
 
-	if strings.Contains(req.URL.Path, "v1beta/openai") {
+	if strings.Contains(req.URL.Path, "v1beta/openai") || isOpenAIChatPath(req.URL.Path) {
 		return ch.BaseChannel.ApplyModelRedirect(req, bodyBytes, group)
 	}
 
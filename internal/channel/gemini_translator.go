@@ -0,0 +1,597 @@
+package channel
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// openAIToGeminiTranslator serves OpenAI chat completions clients from the native Gemini generateContent API.
+type openAIToGeminiTranslator struct{}
+
+// geminiChatRequest extends the OpenAI request with Gemini-only fields that clients may pass through.
+type geminiChatRequest struct {
+	openAIChatRequest
+	SafetySettings []geminiSafetySetting `json:"safety_settings,omitempty"`
+}
+
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig       `json:"toolConfig,omitempty"`
+	SafetySettings    []geminiSafetySetting   `json:"safetySettings,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FileData         *geminiFileData         `json:"fileData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type geminiFunctionCall struct {
+	Name string         `json:"name"`
+	Args map[string]any `json:"args"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig geminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+type geminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type geminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature        *float64        `json:"temperature,omitempty"`
+	TopP               *float64        `json:"topP,omitempty"`
+	MaxOutputTokens    *int            `json:"maxOutputTokens,omitempty"`
+	StopSequences      []string        `json:"stopSequences,omitempty"`
+	CandidateCount     *int            `json:"candidateCount,omitempty"`
+	PresencePenalty    *float64        `json:"presencePenalty,omitempty"`
+	FrequencyPenalty   *float64        `json:"frequencyPenalty,omitempty"`
+	Seed               *int64          `json:"seed,omitempty"`
+	ResponseMimeType   string          `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema json.RawMessage `json:"responseJsonSchema,omitempty"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount     int64 `json:"promptTokenCount"`
+	CandidatesTokenCount int64 `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int64 `json:"thoughtsTokenCount"`
+	TotalTokenCount      int64 `json:"totalTokenCount"`
+}
+
+// toOpenAI converts Gemini usage metadata, counting thinking tokens as completion tokens.
+func (u *geminiUsageMetadata) toOpenAI() openAIUsage {
+	completion := u.CandidatesTokenCount + u.ThoughtsTokenCount
+	total := u.TotalTokenCount
+	if total == 0 {
+		total = u.PromptTokenCount + completion
+	}
+	return openAIUsage{
+		PromptTokens:     u.PromptTokenCount,
+		CompletionTokens: completion,
+		TotalTokens:      total,
+	}
+}
+
+type geminiCandidate struct {
+	Index        int           `json:"index"`
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+}
+
+type geminiResponse struct {
+	Candidates     []geminiCandidate `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback,omitempty"`
+	UsageMetadata *geminiUsageMetadata `json:"usageMetadata,omitempty"`
+	ModelVersion  string               `json:"modelVersion"`
+	ResponseID    string               `json:"responseId"`
+	Error         json.RawMessage      `json:"error,omitempty"`
+}
+
+// TranslateRequest converts an OpenAI chat completions body into a Gemini generateContent body.
+func (t *openAIToGeminiTranslator) TranslateRequest(req *http.Request, bodyBytes []byte, isStream bool) ([]byte, error) {
+	var oaReq geminiChatRequest
+	if err := json.Unmarshal(bodyBytes, &oaReq); err != nil {
+		return nil, fmt.Errorf("invalid chat completions request: %w", err)
+	}
+	model := strings.TrimPrefix(oaReq.Model, "models/")
+	if model == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+
+	gemReq := geminiRequest{SafetySettings: oaReq.SafetySettings}
+
+	// Gemini identifies function responses by name, so remember which call ID belongs to which function.
+	toolNames := make(map[string]string)
+	var systemParts []geminiPart
+	for _, msg := range oaReq.Messages {
+		switch msg.Role {
+		case "system", "developer":
+			if text := openAIContentText(msg.Content); text != "" {
+				systemParts = append(systemParts, geminiPart{Text: text})
+			}
+		case "user":
+			gemReq.Contents = appendGeminiContent(gemReq.Contents, "user", openAIPartsToGeminiParts(msg.Content))
+		case "assistant":
+			parts := openAIPartsToGeminiParts(msg.Content)
+			for _, call := range msg.ToolCalls {
+				toolNames[call.ID] = call.Function.Name
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{
+					Name: call.Function.Name,
+					Args: parseToolArguments(call.Function.Arguments),
+				}})
+			}
+			gemReq.Contents = appendGeminiContent(gemReq.Contents, "model", parts)
+		case "tool", "function":
+			name := toolNames[msg.ToolCallID]
+			if name == "" {
+				name = msg.Name
+			}
+			gemReq.Contents = appendGeminiContent(gemReq.Contents, "user", []geminiPart{{
+				FunctionResponse: &geminiFunctionResponse{
+					Name:     name,
+					Response: toolResultObject(openAIContentText(msg.Content)),
+				},
+			}})
+		}
+	}
+	if len(systemParts) > 0 {
+		gemReq.SystemInstruction = &geminiContent{Parts: systemParts}
+	}
+
+	var declarations []geminiFunctionDeclaration
+	for _, tool := range oaReq.Tools {
+		if tool.Type != "" && tool.Type != "function" {
+			continue
+		}
+		decl := geminiFunctionDeclaration{Name: tool.Function.Name, Description: tool.Function.Description}
+		if len(tool.Function.Parameters) > 0 && string(tool.Function.Parameters) != "null" {
+			var schema any
+			if err := json.Unmarshal(tool.Function.Parameters, &schema); err == nil {
+				decl.Parameters = cleanGeminiSchema(schema)
+			}
+		}
+		declarations = append(declarations, decl)
+	}
+	if len(declarations) > 0 {
+		gemReq.Tools = []geminiTool{{FunctionDeclarations: declarations}}
+		gemReq.ToolConfig = openAIToolChoiceToGemini(oaReq.ToolChoice)
+	}
+
+	genConfig := geminiGenerationConfig{
+		Temperature:      oaReq.Temperature,
+		TopP:             oaReq.TopP,
+		StopSequences:    openAIStopSequences(oaReq.Stop),
+		CandidateCount:   oaReq.N,
+		PresencePenalty:  oaReq.PresencePenalty,
+		FrequencyPenalty: oaReq.FrequencyPenalty,
+		Seed:             oaReq.Seed,
+	}
+	if oaReq.MaxCompletionTokens != nil {
+		genConfig.MaxOutputTokens = oaReq.MaxCompletionTokens
+	} else {
+		genConfig.MaxOutputTokens = oaReq.MaxTokens
+	}
+	if oaReq.ResponseFormat != nil {
+		switch oaReq.ResponseFormat.Type {
+		case "json_object":
+			genConfig.ResponseMimeType = "application/json"
+		case "json_schema":
+			genConfig.ResponseMimeType = "application/json"
+			var wrapper struct {
+				Schema json.RawMessage `json:"schema"`
+			}
+			if err := json.Unmarshal(oaReq.ResponseFormat.JSONSchema, &wrapper); err == nil && len(wrapper.Schema) > 0 {
+				genConfig.ResponseJSONSchema = wrapper.Schema
+			}
+		}
+	}
+	gemReq.GenerationConfig = &genConfig
+
+	method := ":generateContent"
+	if isStream {
+		method = ":streamGenerateContent"
+		q := req.URL.Query()
+		q.Set("alt", "sse")
+		req.URL.RawQuery = q.Encode()
+	}
+	req.URL.Path = geminiModelPath(req.URL.Path, model) + method
+
+	return json.Marshal(gemReq)
+}
+
+// geminiModelPath turns an upstream chat completions path into the native v1beta model path.
+func geminiModelPath(upstreamPath, model string) string {
+	prefix := strings.TrimSuffix(strings.TrimRight(upstreamPath, "/"), "/chat/completions")
+	prefix = strings.TrimSuffix(prefix, "/v1")
+	if !strings.HasSuffix(prefix, "/v1beta") {
+		prefix += "/v1beta"
+	}
+	return prefix + "/models/" + model
+}
+
+// appendGeminiContent adds content to the conversation, merging consecutive turns of the same role.
+func appendGeminiContent(contents []geminiContent, role string, parts []geminiPart) []geminiContent {
+	if len(parts) == 0 {
+		return contents
+	}
+	if n := len(contents); n > 0 && contents[n-1].Role == role {
+		contents[n-1].Parts = append(contents[n-1].Parts, parts...)
+		return contents
+	}
+	return append(contents, geminiContent{Role: role, Parts: parts})
+}
+
+// openAIPartsToGeminiParts converts OpenAI text and image parts into Gemini parts.
+func openAIPartsToGeminiParts(content any) []geminiPart {
+	contentParts := openAIContentParts(content)
+	parts := make([]geminiPart, 0, len(contentParts))
+	for _, part := range contentParts {
+		switch part.Type {
+		case "text":
+			if part.Text != "" {
+				parts = append(parts, geminiPart{Text: part.Text})
+			}
+		case "image_url":
+			if part.ImageURL == nil || part.ImageURL.URL == "" {
+				continue
+			}
+			if mediaType, data, ok := parseDataURL(part.ImageURL.URL); ok {
+				parts = append(parts, geminiPart{InlineData: &geminiBlob{MimeType: mediaType, Data: data}})
+				continue
+			}
+			mimeType := mime.TypeByExtension(path.Ext(strings.SplitN(part.ImageURL.URL, "?", 2)[0]))
+			if mimeType == "" {
+				mimeType = "image/jpeg"
+			}
+			parts = append(parts, geminiPart{FileData: &geminiFileData{MimeType: mimeType, FileURI: part.ImageURL.URL}})
+		}
+	}
+	return parts
+}
+
+// toolResultObject wraps a tool result as the JSON object Gemini expects for function responses.
+func toolResultObject(content string) map[string]any {
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(content), &obj); err == nil && obj != nil {
+		return obj
+	}
+	return map[string]any{"content": content}
+}
+
+// cleanGeminiSchema removes JSON Schema keywords that the Gemini function declaration schema rejects.
+func cleanGeminiSchema(schema any) any {
+	switch v := schema.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, value := range v {
+			switch key {
+			case "$schema", "additionalProperties", "strict", "$id", "$comment":
+				continue
+			}
+			result[key] = cleanGeminiSchema(value)
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			result[i] = cleanGeminiSchema(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// openAIToolChoiceToGemini maps the OpenAI tool_choice field onto Gemini's function calling config.
+func openAIToolChoiceToGemini(choice any) *geminiToolConfig {
+	switch v := choice.(type) {
+	case string:
+		switch v {
+		case "none":
+			return &geminiToolConfig{FunctionCallingConfig: geminiFunctionCallingConfig{Mode: "NONE"}}
+		case "required":
+			return &geminiToolConfig{FunctionCallingConfig: geminiFunctionCallingConfig{Mode: "ANY"}}
+		case "auto":
+			return &geminiToolConfig{FunctionCallingConfig: geminiFunctionCallingConfig{Mode: "AUTO"}}
+		}
+	case map[string]any:
+		if fn, ok := v["function"].(map[string]any); ok {
+			if name, ok := fn["name"].(string); ok && name != "" {
+				return &geminiToolConfig{FunctionCallingConfig: geminiFunctionCallingConfig{
+					Mode:                 "ANY",
+					AllowedFunctionNames: []string{name},
+				}}
+			}
+		}
+	}
+	return nil
+}
+
+// geminiFinishReasonToOpenAI maps a Gemini finishReason onto an OpenAI finish_reason.
+func geminiFinishReasonToOpenAI(reason string, hasToolCalls bool) string {
+	switch reason {
+	case "":
+		return ""
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "content_filter"
+	}
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// geminiCandidateMessage extracts the visible text and function calls of a candidate.
+// Thought parts are dropped because OpenAI clients have no place to render them.
+func geminiCandidateMessage(candidate geminiCandidate, nextToolIndex int) (string, []openAIToolCall) {
+	var text strings.Builder
+	var toolCalls []openAIToolCall
+	for _, part := range candidate.Content.Parts {
+		if part.Thought {
+			continue
+		}
+		if part.Text != "" {
+			text.WriteString(part.Text)
+		}
+		if part.FunctionCall != nil {
+			args := part.FunctionCall.Args
+			if args == nil {
+				args = map[string]any{}
+			}
+			arguments, err := json.Marshal(args)
+			if err != nil {
+				arguments = []byte("{}")
+			}
+			idx := nextToolIndex + len(toolCalls)
+			toolCalls = append(toolCalls, openAIToolCall{
+				Index:    &idx,
+				ID:       fmt.Sprintf("call_%d_%d", time.Now().UnixNano(), idx),
+				Type:     "function",
+				Function: openAIToolCallFunction{Name: part.FunctionCall.Name, Arguments: string(arguments)},
+			})
+		}
+	}
+	return text.String(), toolCalls
+}
+
+// TranslateResponse converts a Gemini generateContent response into an OpenAI chat completion.
+func (t *openAIToGeminiTranslator) TranslateResponse(bodyBytes []byte) ([]byte, error) {
+	var gemResp geminiResponse
+	if err := json.Unmarshal(bodyBytes, &gemResp); err != nil {
+		return nil, fmt.Errorf("invalid generateContent response: %w", err)
+	}
+
+	choices := make([]any, 0, len(gemResp.Candidates))
+	for _, candidate := range gemResp.Candidates {
+		text, toolCalls := geminiCandidateMessage(candidate, 0)
+		for i := range toolCalls {
+			toolCalls[i].Index = nil
+		}
+		message := map[string]any{"role": "assistant", "content": nil}
+		if text != "" || len(toolCalls) == 0 {
+			message["content"] = text
+		}
+		if len(toolCalls) > 0 {
+			message["tool_calls"] = toolCalls
+		}
+		finishReason := geminiFinishReasonToOpenAI(candidate.FinishReason, len(toolCalls) > 0)
+		if finishReason == "" {
+			finishReason = "stop"
+		}
+		choices = append(choices, map[string]any{
+			"index":         candidate.Index,
+			"message":       message,
+			"finish_reason": finishReason,
+		})
+	}
+	if len(choices) == 0 && gemResp.PromptFeedback != nil && gemResp.PromptFeedback.BlockReason != "" {
+		choices = append(choices, map[string]any{
+			"index":         0,
+			"message":       map[string]any{"role": "assistant", "content": ""},
+			"finish_reason": "content_filter",
+		})
+	}
+
+	result := map[string]any{
+		"id":      "chatcmpl-" + gemResp.ResponseID,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   gemResp.ModelVersion,
+		"choices": choices,
+	}
+	if gemResp.UsageMetadata != nil {
+		result["usage"] = gemResp.UsageMetadata.toOpenAI()
+	}
+	return json.Marshal(result)
+}
+
+// NewStreamTranslator creates a converter from Gemini stream chunks to OpenAI chunks.
+func (t *openAIToGeminiTranslator) NewStreamTranslator() StreamTranslator {
+	return &geminiToOpenAIStream{
+		created:       time.Now().Unix(),
+		started:       make(map[int]bool),
+		toolCounts:    make(map[int]int),
+		finishReasons: make(map[int]string),
+	}
+}
+
+// geminiToOpenAIStream tracks per-candidate state while converting Gemini stream chunks.
+type geminiToOpenAIStream struct {
+	id            string
+	model         string
+	created       int64
+	usage         *geminiUsageMetadata
+	started       map[int]bool
+	toolCounts    map[int]int
+	finishReasons map[int]string
+	finished      bool
+}
+
+// chunk builds an OpenAI chat.completion.chunk frame for one candidate.
+func (s *geminiToOpenAIStream) chunk(index int, delta map[string]any, finishReason any) ([]byte, error) {
+	return formatSSEData(map[string]any{
+		"id":      "chatcmpl-" + s.id,
+		"object":  "chat.completion.chunk",
+		"created": s.created,
+		"model":   s.model,
+		"choices": []any{map[string]any{
+			"index":         index,
+			"delta":         delta,
+			"finish_reason": finishReason,
+		}},
+	})
+}
+
+// TranslateEvent converts one Gemini stream chunk into OpenAI chunks.
+func (s *geminiToOpenAIStream) TranslateEvent(data []byte) ([]byte, error) {
+	var gemResp geminiResponse
+	if err := json.Unmarshal(data, &gemResp); err != nil {
+		return nil, fmt.Errorf("invalid stream chunk: %w", err)
+	}
+	if len(gemResp.Error) > 0 {
+		return formatSSEData(map[string]any{"error": gemResp.Error})
+	}
+
+	if s.id == "" {
+		s.id = gemResp.ResponseID
+	}
+	if gemResp.ModelVersion != "" {
+		s.model = gemResp.ModelVersion
+	}
+	if gemResp.UsageMetadata != nil {
+		s.usage = gemResp.UsageMetadata
+	}
+
+	var out []byte
+	for _, candidate := range gemResp.Candidates {
+		delta := map[string]any{}
+		if !s.started[candidate.Index] {
+			s.started[candidate.Index] = true
+			delta["role"] = "assistant"
+		}
+
+		text, toolCalls := geminiCandidateMessage(candidate, s.toolCounts[candidate.Index])
+		if text != "" {
+			delta["content"] = text
+		}
+		if len(toolCalls) > 0 {
+			delta["tool_calls"] = toolCalls
+			s.toolCounts[candidate.Index] += len(toolCalls)
+		}
+		if candidate.FinishReason != "" {
+			s.finishReasons[candidate.Index] = geminiFinishReasonToOpenAI(candidate.FinishReason, s.toolCounts[candidate.Index] > 0)
+		}
+
+		if len(delta) == 0 {
+			continue
+		}
+		frame, err := s.chunk(candidate.Index, delta, nil)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, frame...)
+	}
+	if len(gemResp.Candidates) == 0 && gemResp.PromptFeedback != nil && gemResp.PromptFeedback.BlockReason != "" {
+		s.finishReasons[0] = "content_filter"
+	}
+
+	return out, nil
+}
+
+// Finish emits the closing chunks with finish reasons and the usage totals, then the [DONE] marker.
+// Gemini has no explicit end-of-stream event, so this runs when the upstream stream closes.
+func (s *geminiToOpenAIStream) Finish() []byte {
+	if s.finished {
+		return nil
+	}
+	s.finished = true
+
+	if len(s.finishReasons) == 0 {
+		s.finishReasons[0] = "stop"
+	}
+
+	choices := make([]any, 0, len(s.finishReasons))
+	for index := 0; len(choices) < len(s.finishReasons); index++ {
+		reason, ok := s.finishReasons[index]
+		if !ok {
+			continue
+		}
+		if reason == "" {
+			reason = "stop"
+		}
+		choices = append(choices, map[string]any{
+			"index":         index,
+			"delta":         map[string]any{},
+			"finish_reason": reason,
+		})
+	}
+
+	payload := map[string]any{
+		"id":      "chatcmpl-" + s.id,
+		"object":  "chat.completion.chunk",
+		"created": s.created,
+		"model":   s.model,
+		"choices": choices,
+	}
+	if s.usage != nil {
+		payload["usage"] = s.usage.toOpenAI()
+	}
+
+	frame, err := formatSSEData(payload)
+	if err != nil {
+		return []byte("data: [DONE]\n\n")
+	}
+	return append(frame, []byte("data: [DONE]\n\n")...)
+}
@@ -72,6 +72,17 @@ func applyOpenRouterHeaders(req *http.Request, group *models.Group) {
 	}
 }
 
+// GetTranslator serves Anthropic Messages clients from chat completions when protocol translation is enabled.
+func (ch *OpenAIChannel) GetTranslator(c *gin.Context, group *models.Group) ProtocolTranslator {
+	if !group.EffectiveConfig.EnableProtocolTranslation || c.Request.Method != http.MethodPost {
+		return nil
+	}
+	if isAnthropicMessagesPath(c.Request.URL.Path) {
+		return &anthropicToOpenAITranslator{}
+	}
+	return nil
+}
+
 // ModifyRequest sets the Authorization header for the OpenAI service.
 func (ch *OpenAIChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) {
 	req.Header.Set("Authorization", "Bearer "+apiKey.KeyValue)
@@ -88,6 +99,11 @@ func (ch *OpenAIChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bool
 		return true
 	}
 
+	if contentType := c.GetHeader("Content-Type"); utils.IsMultipartForm(contentType) {
+		stream, _ := utils.GetMultipartField(bodyBytes, contentType, "stream")
+		return stream == "true"
+	}
+
 	type streamPayload struct {
 		Stream bool `json:"stream"`
 	}
@@ -100,14 +116,22 @@ func (ch *OpenAIChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bool
 }
 
 func (ch *OpenAIChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
+	// Audio and image edit uploads carry the model as a form field.
+	if contentType := c.GetHeader("Content-Type"); utils.IsMultipartForm(contentType) {
+		model, _ := utils.GetMultipartField(bodyBytes, contentType, "model")
+		return model
+	}
+
 	type modelPayload struct {
 		Model string `json:"model"`
 	}
 	var p modelPayload
-	if err := json.Unmarshal(bodyBytes, &p); err == nil {
+	if err := json.Unmarshal(bodyBytes, &p); err == nil && p.Model != "" {
 		return p.Model
 	}
-	return ""
+
+	// Realtime sessions pass the model as a query parameter.
+	return c.Query("model")
 }
 
 // isOfficialOpenAI checks if the group is using official OpenAI API
@@ -0,0 +1,539 @@
+package channel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// anthropicToOpenAITranslator serves Anthropic Messages clients from an OpenAI chat completions upstream.
+type anthropicToOpenAITranslator struct{}
+
+// isAnthropicMessagesPath reports whether the path targets the Anthropic Messages endpoint.
+func isAnthropicMessagesPath(path string) bool {
+	return strings.HasSuffix(strings.TrimRight(path, "/"), "/v1/messages")
+}
+
+// anthropicInboundRequest is the subset of the Messages request understood by the translator.
+// System and message content may be either a plain string or a list of content blocks.
+type anthropicInboundRequest struct {
+	Model         string                 `json:"model"`
+	System        json.RawMessage        `json:"system,omitempty"`
+	Messages      []anthropicInboundMsg  `json:"messages"`
+	MaxTokens     *int                   `json:"max_tokens,omitempty"`
+	Temperature   *float64               `json:"temperature,omitempty"`
+	TopP          *float64               `json:"top_p,omitempty"`
+	StopSequences []string               `json:"stop_sequences,omitempty"`
+	Stream        bool                   `json:"stream,omitempty"`
+	Tools         []anthropicInboundTool `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice   `json:"tool_choice,omitempty"`
+	Metadata      *anthropicMetadata     `json:"metadata,omitempty"`
+}
+
+type anthropicInboundMsg struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type anthropicInboundTool struct {
+	Type        string          `json:"type,omitempty"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema,omitempty"`
+}
+
+// anthropicContentBlocks normalizes a content field into blocks. Plain strings become a single text block.
+func anthropicContentBlocks(raw json.RawMessage) []anthropicBlock {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		if text == "" {
+			return nil
+		}
+		return []anthropicBlock{{Type: "text", Text: text}}
+	}
+	var blocks []anthropicBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil
+	}
+	return blocks
+}
+
+// anthropicToolResultText flattens the content of a tool_result block into text.
+func anthropicToolResultText(content any) string {
+	switch v := content.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, block := range anthropicContentBlocks(raw) {
+		if block.Type == "text" {
+			if sb.Len() > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(block.Text)
+		}
+	}
+	return sb.String()
+}
+
+// TranslateRequest converts an Anthropic Messages body into an OpenAI chat completions body.
+func (t *anthropicToOpenAITranslator) TranslateRequest(req *http.Request, bodyBytes []byte, isStream bool) ([]byte, error) {
+	var antReq anthropicInboundRequest
+	if err := json.Unmarshal(bodyBytes, &antReq); err != nil {
+		return nil, fmt.Errorf("invalid messages request: %w", err)
+	}
+
+	oaReq := openAIChatRequest{
+		Model:       antReq.Model,
+		MaxTokens:   antReq.MaxTokens,
+		Temperature: antReq.Temperature,
+		TopP:        antReq.TopP,
+		Stream:      isStream,
+	}
+	if len(antReq.StopSequences) > 0 {
+		oaReq.Stop = antReq.StopSequences
+	}
+	if isStream {
+		oaReq.StreamOptions = &openAIStreamOpts{IncludeUsage: true}
+	}
+	if antReq.Metadata != nil {
+		oaReq.User = antReq.Metadata.UserID
+	}
+
+	var systemText []string
+	for _, block := range anthropicContentBlocks(antReq.System) {
+		if block.Type == "text" && block.Text != "" {
+			systemText = append(systemText, block.Text)
+		}
+	}
+	if len(systemText) > 0 {
+		oaReq.Messages = append(oaReq.Messages, openAIChatMessage{Role: "system", Content: strings.Join(systemText, "\n")})
+	}
+
+	for _, msg := range antReq.Messages {
+		oaReq.Messages = append(oaReq.Messages, anthropicMessageToOpenAI(msg)...)
+	}
+
+	for _, tool := range antReq.Tools {
+		// Server tools such as web search have no chat completions equivalent.
+		if tool.Type != "" && tool.Type != "custom" {
+			continue
+		}
+		schema := tool.InputSchema
+		if len(schema) == 0 || string(schema) == "null" {
+			schema = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		oaReq.Tools = append(oaReq.Tools, openAITool{
+			Type: "function",
+			Function: openAIToolFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  schema,
+			},
+		})
+	}
+	if antReq.ToolChoice != nil && len(oaReq.Tools) > 0 {
+		switch antReq.ToolChoice.Type {
+		case "auto":
+			oaReq.ToolChoice = "auto"
+		case "any":
+			oaReq.ToolChoice = "required"
+		case "none":
+			oaReq.ToolChoice = "none"
+		case "tool":
+			oaReq.ToolChoice = map[string]any{"type": "function", "function": map[string]any{"name": antReq.ToolChoice.Name}}
+		}
+		if antReq.ToolChoice.DisableParallelToolUse {
+			parallel := false
+			oaReq.ParallelToolCalls = &parallel
+		}
+	}
+
+	req.URL.Path = replacePathSuffix(req.URL.Path, "/messages", "/chat/completions")
+	req.Header.Del("anthropic-version")
+	req.Header.Del("anthropic-beta")
+
+	return json.Marshal(oaReq)
+}
+
+// anthropicMessageToOpenAI converts one Messages turn into chat completions messages.
+// Tool results become separate tool messages, which must precede any remaining user content.
+func anthropicMessageToOpenAI(msg anthropicInboundMsg) []openAIChatMessage {
+	var result []openAIChatMessage
+	var parts []openAIContentPart
+	var toolCalls []openAIToolCall
+
+	for _, block := range anthropicContentBlocks(msg.Content) {
+		switch block.Type {
+		case "text":
+			parts = append(parts, openAIContentPart{Type: "text", Text: block.Text})
+		case "image":
+			if block.Source == nil {
+				continue
+			}
+			imageURL := block.Source.URL
+			if block.Source.Type == "base64" {
+				imageURL = "data:" + block.Source.MediaType + ";base64," + block.Source.Data
+			}
+			if imageURL != "" {
+				parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: imageURL}})
+			}
+		case "tool_use":
+			arguments, err := json.Marshal(block.Input)
+			if err != nil || string(arguments) == "null" {
+				arguments = []byte("{}")
+			}
+			toolCalls = append(toolCalls, openAIToolCall{
+				ID:       block.ID,
+				Type:     "function",
+				Function: openAIToolCallFunction{Name: block.Name, Arguments: string(arguments)},
+			})
+		case "tool_result":
+			content := anthropicToolResultText(block.Content)
+			if block.IsError && content != "" {
+				content = "Error: " + content
+			}
+			result = append(result, openAIChatMessage{Role: "tool", ToolCallID: block.ToolUseID, Content: content})
+		}
+	}
+
+	if msg.Role == "assistant" {
+		var text strings.Builder
+		for _, part := range parts {
+			text.WriteString(part.Text)
+		}
+		if text.Len() > 0 || len(toolCalls) > 0 {
+			assistant := openAIChatMessage{Role: "assistant", ToolCalls: toolCalls}
+			if text.Len() > 0 {
+				assistant.Content = text.String()
+			}
+			result = append(result, assistant)
+		}
+		return result
+	}
+
+	if len(parts) > 0 {
+		var content any = parts
+		if len(parts) == 1 && parts[0].Type == "text" {
+			content = parts[0].Text
+		}
+		result = append(result, openAIChatMessage{Role: "user", Content: content})
+	}
+	return result
+}
+
+// openAIFinishReasonToAnthropic maps an OpenAI finish_reason onto an Anthropic stop_reason.
+func openAIFinishReasonToAnthropic(reason string) string {
+	switch reason {
+	case "length":
+		return "max_tokens"
+	case "tool_calls", "function_call":
+		return "tool_use"
+	case "content_filter":
+		return "refusal"
+	default:
+		return "end_turn"
+	}
+}
+
+type openAIChatResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index   int `json:"index"`
+		Message struct {
+			Content   *string          `json:"content"`
+			Refusal   string           `json:"refusal"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+// anthropicUsageFromOpenAI converts OpenAI usage into the Anthropic usage object.
+func anthropicUsageFromOpenAI(usage *openAIUsage) anthropicUsage {
+	if usage == nil {
+		return anthropicUsage{}
+	}
+	return anthropicUsage{InputTokens: usage.PromptTokens, OutputTokens: usage.CompletionTokens}
+}
+
+// TranslateResponse converts an OpenAI chat completion into an Anthropic message.
+func (t *anthropicToOpenAITranslator) TranslateResponse(bodyBytes []byte) ([]byte, error) {
+	var oaResp openAIChatResponse
+	if err := json.Unmarshal(bodyBytes, &oaResp); err != nil {
+		return nil, fmt.Errorf("invalid chat completions response: %w", err)
+	}
+
+	content := []anthropicBlock{}
+	stopReason := "end_turn"
+	if len(oaResp.Choices) > 0 {
+		choice := oaResp.Choices[0]
+		text := choice.Message.Refusal
+		if choice.Message.Content != nil && *choice.Message.Content != "" {
+			text = *choice.Message.Content
+		}
+		if text != "" {
+			content = append(content, anthropicBlock{Type: "text", Text: text})
+		}
+		for _, call := range choice.Message.ToolCalls {
+			content = append(content, anthropicBlock{
+				Type:  "tool_use",
+				ID:    call.ID,
+				Name:  call.Function.Name,
+				Input: parseToolArguments(call.Function.Arguments),
+			})
+		}
+		stopReason = openAIFinishReasonToAnthropic(choice.FinishReason)
+	}
+
+	return json.Marshal(map[string]any{
+		"id":            "msg_" + oaResp.ID,
+		"type":          "message",
+		"role":          "assistant",
+		"model":         oaResp.Model,
+		"content":       content,
+		"stop_reason":   stopReason,
+		"stop_sequence": nil,
+		"usage":         anthropicUsageFromOpenAI(oaResp.Usage),
+	})
+}
+
+// NewStreamTranslator creates a converter from OpenAI chunks to Anthropic stream events.
+func (t *anthropicToOpenAITranslator) NewStreamTranslator() StreamTranslator {
+	return &openAIToAnthropicStream{
+		blockIndex: -1,
+		toolBlocks: make(map[int]int),
+	}
+}
+
+// openAIToAnthropicStream tracks the open content block while converting OpenAI chunks.
+// OpenAI interleaves text and tool call deltas freely, whereas Anthropic requires each
+// content block to be started and stopped explicitly.
+type openAIToAnthropicStream struct {
+	id           string
+	model        string
+	started      bool
+	blockIndex   int
+	blockType    string
+	toolBlocks   map[int]int
+	finishReason string
+	usage        *openAIUsage
+	finished     bool
+}
+
+type openAIStreamChunk struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Content   *string          `json:"content"`
+			Refusal   string           `json:"refusal"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage    `json:"usage"`
+	Error json.RawMessage `json:"error,omitempty"`
+}
+
+// start emits message_start the first time a chunk arrives.
+func (s *openAIToAnthropicStream) start() ([]byte, error) {
+	if s.started {
+		return nil, nil
+	}
+	s.started = true
+	return formatSSEEvent("message_start", map[string]any{
+		"type": "message_start",
+		"message": map[string]any{
+			"id":            "msg_" + s.id,
+			"type":          "message",
+			"role":          "assistant",
+			"model":         s.model,
+			"content":       []any{},
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage":         anthropicUsage{},
+		},
+	})
+}
+
+// closeBlock emits content_block_stop for the currently open block, if any.
+func (s *openAIToAnthropicStream) closeBlock() ([]byte, error) {
+	if s.blockType == "" {
+		return nil, nil
+	}
+	s.blockType = ""
+	return formatSSEEvent("content_block_stop", map[string]any{
+		"type":  "content_block_stop",
+		"index": s.blockIndex,
+	})
+}
+
+// openBlock closes the current block and starts a new one.
+func (s *openAIToAnthropicStream) openBlock(blockType string, block map[string]any) ([]byte, error) {
+	out, err := s.closeBlock()
+	if err != nil {
+		return nil, err
+	}
+	s.blockIndex++
+	s.blockType = blockType
+	frame, err := formatSSEEvent("content_block_start", map[string]any{
+		"type":          "content_block_start",
+		"index":         s.blockIndex,
+		"content_block": block,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, frame...), nil
+}
+
+// delta emits a content_block_delta for the currently open block.
+func (s *openAIToAnthropicStream) delta(delta map[string]any) ([]byte, error) {
+	return formatSSEEvent("content_block_delta", map[string]any{
+		"type":  "content_block_delta",
+		"index": s.blockIndex,
+		"delta": delta,
+	})
+}
+
+// TranslateEvent converts one OpenAI chunk into Anthropic stream events.
+func (s *openAIToAnthropicStream) TranslateEvent(data []byte) ([]byte, error) {
+	var chunk openAIStreamChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return nil, fmt.Errorf("invalid stream chunk: %w", err)
+	}
+	if len(chunk.Error) > 0 {
+		return formatSSEEvent("error", map[string]any{
+			"type":  "error",
+			"error": map[string]any{"type": "api_error", "message": string(chunk.Error)},
+		})
+	}
+
+	if s.id == "" {
+		s.id = chunk.ID
+	}
+	if chunk.Model != "" {
+		s.model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		s.usage = chunk.Usage
+	}
+
+	var out []byte
+	emit := func(frame []byte, err error) error {
+		if err != nil {
+			return err
+		}
+		out = append(out, frame...)
+		return nil
+	}
+
+	if err := emit(s.start()); err != nil {
+		return nil, err
+	}
+
+	for _, choice := range chunk.Choices {
+		// Anthropic messages have a single candidate, so extra choices are dropped.
+		if choice.Index != 0 {
+			continue
+		}
+
+		text := choice.Delta.Refusal
+		if choice.Delta.Content != nil && *choice.Delta.Content != "" {
+			text = *choice.Delta.Content
+		}
+		if text != "" {
+			if s.blockType != "text" {
+				if err := emit(s.openBlock("text", map[string]any{"type": "text", "text": ""})); err != nil {
+					return nil, err
+				}
+			}
+			if err := emit(s.delta(map[string]any{"type": "text_delta", "text": text})); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, call := range choice.Delta.ToolCalls {
+			toolIndex := 0
+			if call.Index != nil {
+				toolIndex = *call.Index
+			}
+			blockIndex, known := s.toolBlocks[toolIndex]
+			if !known {
+				if err := emit(s.openBlock("tool_use", map[string]any{
+					"type":  "tool_use",
+					"id":    call.ID,
+					"name":  call.Function.Name,
+					"input": map[string]any{},
+				})); err != nil {
+					return nil, err
+				}
+				s.toolBlocks[toolIndex] = s.blockIndex
+				blockIndex = s.blockIndex
+			}
+			// Arguments for a tool call that is no longer the open block cannot be delivered in order.
+			if call.Function.Arguments == "" || blockIndex != s.blockIndex || s.blockType != "tool_use" {
+				continue
+			}
+			if err := emit(s.delta(map[string]any{"type": "input_json_delta", "partial_json": call.Function.Arguments})); err != nil {
+				return nil, err
+			}
+		}
+
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			s.finishReason = *choice.FinishReason
+		}
+	}
+
+	return out, nil
+}
+
+// Finish closes the open block and emits message_delta with the stop reason and usage, then message_stop.
+// OpenAI sends usage after the finish_reason chunk, so the final events wait for the end of the stream.
+func (s *openAIToAnthropicStream) Finish() []byte {
+	if s.finished {
+		return nil
+	}
+	s.finished = true
+
+	var out []byte
+	if frame, err := s.start(); err == nil {
+		out = append(out, frame...)
+	}
+	if frame, err := s.closeBlock(); err == nil {
+		out = append(out, frame...)
+	}
+
+	usage := anthropicUsageFromOpenAI(s.usage)
+	if frame, err := formatSSEEvent("message_delta", map[string]any{
+		"type": "message_delta",
+		"delta": map[string]any{
+			"stop_reason":   openAIFinishReasonToAnthropic(s.finishReason),
+			"stop_sequence": nil,
+		},
+		"usage": map[string]any{
+			"input_tokens":  usage.InputTokens,
+			"output_tokens": usage.OutputTokens,
+		},
+	}); err == nil {
+		out = append(out, frame...)
+	}
+	if frame, err := formatSSEEvent("message_stop", map[string]any{"type": "message_stop"}); err == nil {
+		out = append(out, frame...)
+	}
+	return out
+}
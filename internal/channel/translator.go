@@ -0,0 +1,226 @@
+package channel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ProtocolTranslator converts requests written for one API format into a channel's native
+// format and converts the upstream responses back, so clients can keep a single SDK.
+type ProtocolTranslator interface {
+	// TranslateRequest rewrites the upstream request path and returns the translated body.
+	TranslateRequest(req *http.Request, bodyBytes []byte, isStream bool) ([]byte, error)
+
+	// TranslateResponse converts a complete, successful upstream response body into the client format.
+	TranslateResponse(bodyBytes []byte) ([]byte, error)
+
+	// NewStreamTranslator creates a converter for a single upstream event stream.
+	NewStreamTranslator() StreamTranslator
+}
+
+// StreamTranslator converts upstream SSE events into client SSE frames.
+// A new instance is created for every streamed response because conversion is stateful.
+type StreamTranslator interface {
+	// TranslateEvent converts the payload of one upstream "data:" line into the bytes written to the client.
+	TranslateEvent(data []byte) ([]byte, error)
+
+	// Finish returns the trailing bytes to write once the upstream stream has ended.
+	Finish() []byte
+}
+
+// isOpenAIChatPath reports whether the path targets the OpenAI chat completions endpoint.
+func isOpenAIChatPath(path string) bool {
+	return strings.HasSuffix(strings.TrimRight(path, "/"), "/chat/completions")
+}
+
+// replacePathSuffix swaps the trailing client endpoint of an upstream path for the native one.
+func replacePathSuffix(path, oldSuffix, newSuffix string) string {
+	trimmed := strings.TrimRight(path, "/")
+	if strings.HasSuffix(trimmed, oldSuffix) {
+		return strings.TrimSuffix(trimmed, oldSuffix) + newSuffix
+	}
+	return trimmed + newSuffix
+}
+
+// formatSSEData encodes a payload as a single SSE data frame.
+func formatSSEData(payload any) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal stream event: %w", err)
+	}
+	return []byte("data: " + string(data) + "\n\n"), nil
+}
+
+// formatSSEEvent encodes a payload as a named SSE event frame.
+func formatSSEEvent(event string, payload any) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal stream event: %w", err)
+	}
+	return []byte("event: " + event + "\ndata: " + string(data) + "\n\n"), nil
+}
+
+// openAIChatRequest is the subset of the OpenAI chat completions request understood by translators.
+type openAIChatRequest struct {
+	Model               string              `json:"model"`
+	Messages            []openAIChatMessage `json:"messages"`
+	MaxTokens           *int                `json:"max_tokens,omitempty"`
+	MaxCompletionTokens *int                `json:"max_completion_tokens,omitempty"`
+	Temperature         *float64            `json:"temperature,omitempty"`
+	TopP                *float64            `json:"top_p,omitempty"`
+	Stop                any                 `json:"stop,omitempty"`
+	Stream              bool                `json:"stream,omitempty"`
+	StreamOptions       *openAIStreamOpts   `json:"stream_options,omitempty"`
+	Tools               []openAITool        `json:"tools,omitempty"`
+	ToolChoice          any                 `json:"tool_choice,omitempty"`
+	ParallelToolCalls   *bool               `json:"parallel_tool_calls,omitempty"`
+	User                string              `json:"user,omitempty"`
+	N                   *int                `json:"n,omitempty"`
+	PresencePenalty     *float64            `json:"presence_penalty,omitempty"`
+	FrequencyPenalty    *float64            `json:"frequency_penalty,omitempty"`
+	Seed                *int64              `json:"seed,omitempty"`
+	ResponseFormat      *openAIRespFormat   `json:"response_format,omitempty"`
+}
+
+type openAIStreamOpts struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIRespFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema json.RawMessage `json:"json_schema,omitempty"`
+}
+
+type openAIChatMessage struct {
+	Role       string           `json:"role"`
+	Content    any              `json:"content"`
+	Name       string           `json:"name,omitempty"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type openAITool struct {
+	Type     string             `json:"type"`
+	Function openAIToolFunction `json:"function"`
+}
+
+type openAIToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type openAIToolCall struct {
+	Index    *int                   `json:"index,omitempty"`
+	ID       string                 `json:"id,omitempty"`
+	Type     string                 `json:"type,omitempty"`
+	Function openAIToolCallFunction `json:"function"`
+}
+
+type openAIToolCallFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// openAIContentParts normalizes a message content field into a list of parts.
+// Plain string content becomes a single text part.
+func openAIContentParts(content any) []openAIContentPart {
+	switch v := content.(type) {
+	case nil:
+		return nil
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []openAIContentPart{{Type: "text", Text: v}}
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		var parts []openAIContentPart
+		if err := json.Unmarshal(raw, &parts); err != nil {
+			return nil
+		}
+		return parts
+	}
+}
+
+// openAIContentText concatenates the text parts of a message content field.
+func openAIContentText(content any) string {
+	var sb strings.Builder
+	for _, part := range openAIContentParts(content) {
+		if part.Type == "text" {
+			if sb.Len() > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String()
+}
+
+// openAIStopSequences normalizes the "stop" field, which may be a string or a list.
+func openAIStopSequences(stop any) []string {
+	switch v := stop.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []any:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// parseDataURL splits a base64 data URL into its media type and payload.
+func parseDataURL(rawURL string) (mediaType string, data string, ok bool) {
+	if !strings.HasPrefix(rawURL, "data:") {
+		return "", "", false
+	}
+	header, payload, found := strings.Cut(strings.TrimPrefix(rawURL, "data:"), ",")
+	if !found || !strings.HasSuffix(header, ";base64") {
+		return "", "", false
+	}
+	return strings.TrimSuffix(header, ";base64"), payload, true
+}
+
+// parseToolArguments decodes a JSON-encoded arguments string into an object.
+// Invalid or empty arguments fall back to an empty object, which every upstream accepts.
+func parseToolArguments(arguments string) map[string]any {
+	result := map[string]any{}
+	if strings.TrimSpace(arguments) == "" {
+		return result
+	}
+	if err := json.Unmarshal([]byte(arguments), &result); err != nil || result == nil {
+		return map[string]any{}
+	}
+	return result
+}
@@ -0,0 +1,96 @@
+package channel
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Upstream selection strategies, set per group by upstream_selection_strategy.
+const (
+	UpstreamSelectionWeighted = "weighted"
+	UpstreamSelectionLatency  = "latency"
+)
+
+// ewmaAlpha is the weight of the newest sample in the moving averages of upstream latency and errors.
+const ewmaAlpha = 0.2
+
+// errorLatencyPenalty scales the latency of an upstream by its error rate when ranking upstreams:
+// at a 10% error rate an upstream looks twice as slow as it answers.
+const errorLatencyPenalty = 10
+
+// upstreamStats holds the moving averages of an upstream's latency and error rate.
+// It is guarded by the channel's upstreamLock.
+type upstreamStats struct {
+	latencyMs      float64 // Time to response headers of successful requests
+	errorRate      float64 // 1 for a failed request, 0 for a successful one
+	samples        int
+	latencySamples int
+}
+
+// record adds the outcome of a request to the moving averages. Failures are often fast, so only
+// successful requests count towards the latency.
+func (s *upstreamStats) record(failed bool, latency time.Duration) {
+	errorSample := 0.0
+	if failed {
+		errorSample = 1
+	}
+	if s.samples == 0 {
+		s.errorRate = errorSample
+	} else {
+		s.errorRate += ewmaAlpha * (errorSample - s.errorRate)
+	}
+	s.samples++
+
+	if failed {
+		return
+	}
+	latencyMs := float64(latency) / float64(time.Millisecond)
+	if s.latencySamples == 0 {
+		s.latencyMs = latencyMs
+	} else {
+		s.latencyMs += ewmaAlpha * (latencyMs - s.latencyMs)
+	}
+	s.latencySamples++
+}
+
+// score ranks upstreams for the latency strategy, lowest first. Upstreams without samples come first
+// so every upstream gets measured; upstreams that never answered come last.
+func (s *upstreamStats) score() float64 {
+	if s.samples == 0 {
+		return 0
+	}
+	if s.latencySamples == 0 {
+		return math.Inf(1)
+	}
+	return s.latencyMs * (1 + errorLatencyPenalty*s.errorRate)
+}
+
+// pickFastestUpstream returns the eligible upstream with the best score. A share of the requests set by
+// upstream_exploration_percent goes to another eligible upstream instead, so the stats of the others
+// stay current and a recovered upstream gets noticed. The caller holds upstreamLock.
+func (b *BaseChannel) pickFastestUpstream(eligible func(up *UpstreamInfo) bool) *UpstreamInfo {
+	var candidates []*UpstreamInfo
+	var best *UpstreamInfo
+	for i := range b.Upstreams {
+		up := &b.Upstreams[i]
+		if !eligible(up) {
+			continue
+		}
+		candidates = append(candidates, up)
+		if best == nil || up.stats.score() < best.stats.score() {
+			best = up
+		}
+	}
+
+	if len(candidates) > 1 && rand.Intn(100) < b.explorationPercent {
+		others := make([]*UpstreamInfo, 0, len(candidates)-1)
+		for _, up := range candidates {
+			if up != best {
+				others = append(others, up)
+			}
+		}
+		return others[rand.Intn(len(others))]
+	}
+	return best
+}
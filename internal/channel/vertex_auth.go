@@ -0,0 +1,280 @@
+package channel
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultGoogleTokenURI = "https://oauth2.googleapis.com/token"
+	vertexOAuthScope      = "https://www.googleapis.com/auth/cloud-platform"
+	jwtBearerGrantType    = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	// tokenRefreshMargin renews cached tokens shortly before they expire.
+	tokenRefreshMargin = 5 * time.Minute
+	// tokenCacheSweepInterval is how often expired tokens are dropped from the cache.
+	tokenCacheSweepInterval = 10 * time.Minute
+)
+
+// serviceAccountKey is the subset of a Google service-account JSON key used for the JWT bearer grant.
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// parseServiceAccountKey decodes and checks a service-account JSON key.
+func parseServiceAccountKey(keyValue string) (*serviceAccountKey, error) {
+	var sa serviceAccountKey
+	if err := json.Unmarshal([]byte(keyValue), &sa); err != nil {
+		return nil, fmt.Errorf("key is not a valid service account JSON: %w", err)
+	}
+	if sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, fmt.Errorf("service account JSON must contain client_email and private_key")
+	}
+	if sa.ProjectID == "" {
+		return nil, fmt.Errorf("service account JSON must contain project_id")
+	}
+	return &sa, nil
+}
+
+// tokenEndpoint resolves the endpoint to exchange tokens with: an explicit override,
+// then the key's token_uri, then Google's default endpoint.
+func (sa *serviceAccountKey) tokenEndpoint(override string) string {
+	if override != "" {
+		return override
+	}
+	if sa.TokenURI != "" {
+		return sa.TokenURI
+	}
+	return defaultGoogleTokenURI
+}
+
+// signedJWT builds the RS256-signed assertion for the JWT bearer grant.
+func (sa *serviceAccountKey) signedJWT(audience string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("service account private_key is not PEM encoded")
+	}
+	var rsaKey *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		key, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("service account private_key is not an RSA key")
+		}
+		rsaKey = key
+	} else if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		rsaKey = key
+	} else {
+		return "", fmt.Errorf("failed to parse service account private_key: %w", err)
+	}
+
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if sa.PrivateKeyID != "" {
+		header["kid"] = sa.PrivateKeyID
+	}
+	claims := map[string]any{
+		"iss":   sa.ClientEmail,
+		"scope": vertexOAuthScope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// cachedAccessToken is a minted access token and its expiry.
+type cachedAccessToken struct {
+	mu        sync.Mutex
+	keyHash   string // Hash of the key the token was minted for
+	token     string
+	expiresAt time.Time
+}
+
+// accessTokenCache mints and caches OAuth access tokens per service-account key.
+// Entries are keyed by a hash of the key and token endpoint, so the cache survives
+// channel rebuilds and tokens are never shared between different credentials.
+// Expired tokens are swept out, so keys that are no longer used do not stay cached.
+type accessTokenCache struct {
+	mu        sync.Mutex
+	entries   map[string]*cachedAccessToken
+	lastSweep time.Time
+}
+
+var vertexTokenCache = &accessTokenCache{entries: make(map[string]*cachedAccessToken)}
+
+// hashKeyValue returns the hex SHA-256 of a key, so cache entries never hold it in plain text.
+func hashKeyValue(keyValue string) string {
+	sum := sha256.Sum256([]byte(keyValue))
+	return hex.EncodeToString(sum[:])
+}
+
+// entry returns the cache slot for a key, creating it on first use.
+func (c *accessTokenCache) entry(keyValue, tokenEndpoint string) *cachedAccessToken {
+	sum := sha256.Sum256([]byte(tokenEndpoint + "\x00" + keyValue))
+	id := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(time.Now())
+	e, ok := c.entries[id]
+	if !ok {
+		e = &cachedAccessToken{keyHash: hashKeyValue(keyValue)}
+		c.entries[id] = e
+	}
+	return e
+}
+
+// sweep drops the entries whose token has expired, at most once per tokenCacheSweepInterval. Entries busy
+// minting a token are kept. The caller holds c.mu.
+func (c *accessTokenCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < tokenCacheSweepInterval {
+		return
+	}
+	c.lastSweep = now
+	for id, e := range c.entries {
+		if !e.mu.TryLock() {
+			continue
+		}
+		if now.After(e.expiresAt) {
+			delete(c.entries, id)
+		}
+		e.mu.Unlock()
+	}
+}
+
+// forget drops the cached tokens of a key for every token endpoint.
+func (c *accessTokenCache) forget(keyValue string) {
+	keyHash := hashKeyValue(keyValue)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, e := range c.entries {
+		if e.keyHash == keyHash {
+			delete(c.entries, id)
+		}
+	}
+}
+
+// ForgetKeyCredentials drops the credentials cached for a key that was removed from its group.
+func ForgetKeyCredentials(keyValue string) {
+	vertexTokenCache.forget(keyValue)
+}
+
+// Token returns a valid access token for the key, minting a new one when the cached token is about to expire.
+// Concurrent callers for the same key wait for a single exchange instead of each minting their own token.
+func (c *accessTokenCache) Token(ctx context.Context, client *http.Client, keyValue, tokenEndpoint string) (string, error) {
+	sa, err := parseServiceAccountKey(keyValue)
+	if err != nil {
+		return "", err
+	}
+	tokenEndpoint = sa.tokenEndpoint(tokenEndpoint)
+
+	e := c.entry(keyValue, tokenEndpoint)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.token != "" && time.Now().Add(tokenRefreshMargin).Before(e.expiresAt) {
+		return e.token, nil
+	}
+
+	token, expiresIn, err := exchangeJWTBearer(ctx, client, sa, tokenEndpoint)
+	if err != nil {
+		return "", err
+	}
+	e.token = token
+	e.expiresAt = time.Now().Add(expiresIn)
+	return token, nil
+}
+
+// Invalidate drops the cached token for a key, e.g. after the upstream rejected it.
+func (c *accessTokenCache) Invalidate(keyValue, tokenEndpoint string) {
+	sa, err := parseServiceAccountKey(keyValue)
+	if err != nil {
+		return
+	}
+	e := c.entry(keyValue, sa.tokenEndpoint(tokenEndpoint))
+	e.mu.Lock()
+	e.token = ""
+	e.mu.Unlock()
+}
+
+// exchangeJWTBearer performs the OAuth 2.0 JWT bearer grant against the token endpoint.
+func exchangeJWTBearer(ctx context.Context, client *http.Client, sa *serviceAccountKey, tokenEndpoint string) (string, time.Duration, error) {
+	assertion, err := sa.signedJWT(tokenEndpoint, time.Now())
+	if err != nil {
+		return "", 0, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", jwtBearerGrantType)
+	form.Set("assertion", assertion)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("token exchange failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", 0, fmt.Errorf("token exchange failed: [status %d] %s", resp.StatusCode, app_errors.ParseUpstreamError(body))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", 0, fmt.Errorf("invalid token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", 0, fmt.Errorf("token exchange returned no access_token")
+	}
+	if tokenResp.ExpiresIn <= 0 {
+		tokenResp.ExpiresIn = 3600
+	}
+	return tokenResp.AccessToken, time.Duration(tokenResp.ExpiresIn) * time.Second, nil
+}
@@ -0,0 +1,211 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultVertexLocation = "us-central1"
+
+func init() {
+	Register("vertex", newVertexChannel)
+}
+
+// VertexChannel proxies Gemini requests to Vertex AI publisher models.
+// Keys are service-account JSON blobs, exchanged for cached OAuth access tokens.
+// Upstreams are regional endpoints such as https://us-central1-aiplatform.googleapis.com;
+// the optional "location" and "token_endpoint" query parameters on the upstream URL override
+// the location derived from the host and the token_uri of the service account.
+type VertexChannel struct {
+	*GeminiChannel
+	upstreamSettings map[string]vertexUpstreamSettings
+}
+
+type vertexUpstreamSettings struct {
+	location      string
+	tokenEndpoint string
+}
+
+func newVertexChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	base, err := f.newBaseChannel("vertex", group)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]vertexUpstreamSettings, len(base.Upstreams))
+	for _, up := range base.Upstreams {
+		settings[up.URL.Host] = vertexSettingsFor(up.URL)
+	}
+
+	return &VertexChannel{
+		GeminiChannel:    &GeminiChannel{BaseChannel: base},
+		upstreamSettings: settings,
+	}, nil
+}
+
+// vertexSettingsFor derives the location and token endpoint for an upstream URL.
+func vertexSettingsFor(u *url.URL) vertexUpstreamSettings {
+	settings := vertexUpstreamSettings{
+		location:      u.Query().Get("location"),
+		tokenEndpoint: u.Query().Get("token_endpoint"),
+	}
+	if settings.location == "" {
+		host := u.Hostname()
+		switch {
+		case host == "aiplatform.googleapis.com":
+			settings.location = "global"
+		case strings.HasSuffix(host, "-aiplatform.googleapis.com"):
+			settings.location = strings.TrimSuffix(host, "-aiplatform.googleapis.com")
+		default:
+			settings.location = defaultVertexLocation
+		}
+	}
+	return settings
+}
+
+// settingsFor returns the settings of the upstream a request is sent to.
+func (ch *VertexChannel) settingsFor(u *url.URL) vertexUpstreamSettings {
+	if settings, ok := ch.upstreamSettings[u.Host]; ok {
+		return settings
+	}
+	return vertexSettingsFor(u)
+}
+
+// vertexModelPath rewrites a Gemini API path (e.g. /v1beta/models/gemini-2.0-flash:generateContent)
+// into the Vertex publisher model path of the given project and location.
+// Paths that already address a Vertex resource are returned unchanged.
+func vertexModelPath(path, project, location string) string {
+	if strings.Contains(path, "/projects/") {
+		return path
+	}
+	idx := strings.Index(path, "/models/")
+	if idx == -1 {
+		return path
+	}
+	head := strings.TrimSuffix(strings.TrimSuffix(path[:idx], "/v1beta"), "/v1")
+	return head + "/v1/projects/" + project + "/locations/" + location + "/publishers/google" + path[idx:]
+}
+
+// ModifyRequest maps the request onto the key's project. The access token is added by AuthorizeRequest.
+func (ch *VertexChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) {
+	sa, err := parseServiceAccountKey(apiKey.KeyValue)
+	if err != nil {
+		logrus.WithField("group", group.Name).Warnf("Vertex key %s: %v", utils.MaskAPIKey(apiKey.KeyValue), err)
+		return
+	}
+	settings := ch.settingsFor(req.URL)
+
+	req.URL.Path = vertexModelPath(req.URL.Path, sa.ProjectID, settings.location)
+	req.URL.RawPath = ""
+	q := req.URL.Query()
+	q.Del("key")
+	req.URL.RawQuery = q.Encode()
+}
+
+// AuthorizeRequest authenticates the request with an access token for the key. A key that is not a valid
+// service account is sent without one, so the upstream's refusal counts against it.
+func (ch *VertexChannel) AuthorizeRequest(req *http.Request, apiKey *models.APIKey) error {
+	if _, err := parseServiceAccountKey(apiKey.KeyValue); err != nil {
+		return nil
+	}
+	token, err := vertexTokenCache.Token(req.Context(), ch.HTTPClient, apiKey.KeyValue, ch.settingsFor(req.URL).tokenEndpoint)
+	if err != nil {
+		return fmt.Errorf("failed to obtain Vertex access token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// RejectAuthorization drops the cached access token of the key, which may have been revoked.
+func (ch *VertexChannel) RejectAuthorization(req *http.Request, apiKey *models.APIKey) {
+	vertexTokenCache.Invalidate(apiKey.KeyValue, ch.settingsFor(req.URL).tokenEndpoint)
+}
+
+// ValidateKey checks that the token exchange succeeds and that the test model can generate content.
+func (ch *VertexChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
+	upstreamURL := ch.getUpstreamURL()
+	if upstreamURL == nil {
+		return false, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	sa, err := parseServiceAccountKey(apiKey.KeyValue)
+	if err != nil {
+		return false, err
+	}
+	settings := ch.settingsFor(upstreamURL)
+
+	token, err := vertexTokenCache.Token(ctx, ch.HTTPClient, apiKey.KeyValue, settings.tokenEndpoint)
+	if err != nil {
+		return false, err
+	}
+
+	finalURL := *upstreamURL
+	finalURL.RawQuery = ""
+	finalURL.Path = vertexModelPath(strings.TrimRight(finalURL.Path, "/")+"/models/"+ch.TestModel+":generateContent", sa.ProjectID, settings.location)
+
+	payload := gin.H{
+		"contents": []gin.H{
+			{
+				"role": "user",
+				"parts": []gin.H{
+					{"text": "hi"},
+				},
+			},
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal validation payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", finalURL.String(), bytes.NewBuffer(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create validation request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	// Apply custom header rules if available
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := ch.HTTPClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send validation request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Any 2xx status code indicates the key is valid.
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, nil
+	}
+
+	// A rejected token may have been revoked, so mint a fresh one next time.
+	if resp.StatusCode == http.StatusUnauthorized {
+		vertexTokenCache.Invalidate(apiKey.KeyValue, settings.tokenEndpoint)
+	}
+
+	// For non-200 responses, parse the body to provide a more specific error reason.
+	errorBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("key is invalid (status %d), but failed to read error body: %w", resp.StatusCode, err)
+	}
+
+	parsedError := app_errors.ParseUpstreamError(errorBody)
+
+	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+}
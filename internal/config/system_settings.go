@@ -12,6 +12,7 @@ import (
 	"gpt-load/internal/utils"
 	"os"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -290,6 +291,13 @@ func (sm *SystemSettingsManager) ValidateSettings(settingsMap map[string]any) er
 						return fmt.Errorf("value for %s (%d) is below minimum value (%d)", key, intVal, minVal)
 					}
 				}
+				if strings.HasPrefix(trimmedRule, "max=") {
+					maxValStr := strings.TrimPrefix(trimmedRule, "max=")
+					maxVal, _ := strconv.Atoi(maxValStr)
+					if intVal > maxVal {
+						return fmt.Errorf("value for %s (%d) is above maximum value (%d)", key, intVal, maxVal)
+					}
+				}
 			}
 		case reflect.Bool:
 			if _, ok := value.(bool); !ok {
@@ -307,6 +315,9 @@ func (sm *SystemSettingsManager) ValidateSettings(settingsMap map[string]any) er
 						return fmt.Errorf("value for %s is required", key)
 					}
 				}
+				if err := validateStringRule(trimmedRule, strVal); err != nil {
+					return fmt.Errorf("invalid value for %s: %w", key, err)
+				}
 			}
 		default:
 			return fmt.Errorf("unsupported type for setting key validation: %s", key)
@@ -364,6 +375,13 @@ func (sm *SystemSettingsManager) ValidateGroupConfigOverrides(configMap map[stri
 						return fmt.Errorf("value for %s (%d) is below minimum value (%d)", key, intVal, minVal)
 					}
 				}
+				if strings.HasPrefix(trimmedRule, "max=") {
+					maxValStr := strings.TrimPrefix(trimmedRule, "max=")
+					maxVal, _ := strconv.Atoi(maxValStr)
+					if intVal > maxVal {
+						return fmt.Errorf("value for %s (%d) is above maximum value (%d)", key, intVal, maxVal)
+					}
+				}
 			}
 		case reflect.String:
 			strVal, ok := value.(string)
@@ -377,6 +395,9 @@ func (sm *SystemSettingsManager) ValidateGroupConfigOverrides(configMap map[stri
 						return fmt.Errorf("value for %s is required", key)
 					}
 				}
+				if err := validateStringRule(trimmedRule, strVal); err != nil {
+					return fmt.Errorf("invalid value for %s: %w", key, err)
+				}
 			}
 		case reflect.Bool:
 			_, ok := value.(bool)
@@ -391,6 +412,22 @@ func (sm *SystemSettingsManager) ValidateGroupConfigOverrides(configMap map[stri
 	return nil
 }
 
+// validateStringRule checks string settings with a fixed set of values or a structured format against their validate rule.
+func validateStringRule(rule, value string) error {
+	switch rule {
+	case "status_codes":
+		_, err := utils.ParseStatusCodeSet(value)
+		return err
+	case "error_classes":
+		_, err := utils.ParseErrorClasses(value)
+		return err
+	}
+	if options, ok := strings.CutPrefix(rule, "oneof="); ok && !slices.Contains(strings.Fields(options), value) {
+		return fmt.Errorf("must be one of: %s", options)
+	}
+	return nil
+}
+
 // DisplaySystemConfig displays the current system settings.
 func (sm *SystemSettingsManager) DisplaySystemConfig(settings types.SystemSettings) {
 	logrus.Info("")
@@ -21,7 +21,12 @@ func MigrateDatabase(db *gorm.DB) error {
 	}
 
 	// Run v1.3.0 migration
-	return V1_3_0_AddTokensColumns(db)
+	if err := V1_3_0_AddTokensColumns(db); err != nil {
+		return err
+	}
+
+	// Run v1.4.0 migration
+	return V1_4_0_AddKeyTierColumn(db)
 }
 
 // HandleLegacyIndexes removes old indexes from previous versions to prevent migration errors
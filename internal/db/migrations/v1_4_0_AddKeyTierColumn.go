@@ -0,0 +1,31 @@
+package db
+
+import (
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// V1_4_0_AddKeyTierColumn adds the priority tier column to api_keys table
+func V1_4_0_AddKeyTierColumn(db *gorm.DB) error {
+	// Check if migration is needed
+	if db.Migrator().HasColumn(&models.APIKey{}, "tier") {
+		logrus.Info("Tier column already exists, skipping v1.4.0...")
+		return nil
+	}
+
+	logrus.Info("Running migration v1.4.0: Adding tier column to api_keys table...")
+
+	if err := db.AutoMigrate(&models.APIKey{}); err != nil {
+		return err
+	}
+
+	// Existing keys become primary keys
+	if err := db.Model(&models.APIKey{}).Where("tier IS NULL").Update("tier", 0).Error; err != nil {
+		return err
+	}
+
+	logrus.Info("Migration v1.4.0 completed successfully")
+	return nil
+}
@@ -38,6 +38,8 @@ var (
 	ErrNoActiveKeys       = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_ACTIVE_KEYS", Message: "No active API keys available for this group"}
 	ErrMaxRetriesExceeded = &APIError{HTTPStatus: http.StatusBadGateway, Code: "MAX_RETRIES_EXCEEDED", Message: "Request failed after maximum retries"}
 	ErrNoKeysAvailable    = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_KEYS_AVAILABLE", Message: "No API keys available to process the request"}
+	ErrKeysCoolingDown    = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "KEYS_COOLING_DOWN", Message: "All API keys are rate limited, please retry later"}
+	ErrKeysSaturated      = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "KEYS_SATURATED", Message: "All API keys are at their concurrency limit, please retry later"}
 )
 
 // NewAPIError creates a new APIError with a custom message.
@@ -0,0 +1,33 @@
+package errors
+
+import "strings"
+
+// modelAccessSubstrings contains substrings of upstream errors telling that the key cannot use the
+// requested model, while the key itself is fine for other models.
+var modelAccessSubstrings = []string{
+	"model_not_found",
+	"model not found",
+	"does not exist or you do not have access",
+	"do not have access to the model",
+	"does not have access to model",
+	"must be verified to use the model",
+	"is not found for api version",
+	"not allowed to use model",
+}
+
+// IsModelAccessError checks if the given error message reports that the model is unavailable to the key
+func IsModelAccessError(errorMsg string) bool {
+	if errorMsg == "" {
+		return false
+	}
+
+	errorLower := strings.ToLower(errorMsg)
+
+	for _, pattern := range modelAccessSubstrings {
+		if strings.Contains(errorLower, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -58,6 +58,7 @@ type GroupCreateRequest struct {
 	ModelRedirectRules  map[string]string   `json:"model_redirect_rules"`
 	ModelRedirectStrict bool                `json:"model_redirect_strict"`
 	Config              map[string]any      `json:"config"`
+	ChannelConfig       map[string]any      `json:"channel_config"`
 	HeaderRules         []models.HeaderRule `json:"header_rules"`
 	ProxyKeys           string              `json:"proxy_keys"`
 }
@@ -84,6 +85,7 @@ func (s *Server) CreateGroup(c *gin.Context) {
 		ModelRedirectRules:  req.ModelRedirectRules,
 		ModelRedirectStrict: req.ModelRedirectStrict,
 		Config:              req.Config,
+		ChannelConfig:       req.ChannelConfig,
 		HeaderRules:         req.HeaderRules,
 		ProxyKeys:           req.ProxyKeys,
 	}
@@ -127,6 +129,7 @@ type GroupUpdateRequest struct {
 	ModelRedirectRules  map[string]string   `json:"model_redirect_rules"`
 	ModelRedirectStrict *bool               `json:"model_redirect_strict"`
 	Config              map[string]any      `json:"config"`
+	ChannelConfig       map[string]any      `json:"channel_config"`
 	HeaderRules         []models.HeaderRule `json:"header_rules"`
 	ProxyKeys           *string             `json:"proxy_keys,omitempty"`
 }
@@ -157,6 +160,7 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 		ModelRedirectRules:  req.ModelRedirectRules,
 		ModelRedirectStrict: req.ModelRedirectStrict,
 		Config:              req.Config,
+		ChannelConfig:       req.ChannelConfig,
 		ProxyKeys:           req.ProxyKeys,
 	}
 
@@ -200,6 +204,7 @@ type GroupResponse struct {
 	ModelRedirectRules  datatypes.JSONMap   `json:"model_redirect_rules"`
 	ModelRedirectStrict bool                `json:"model_redirect_strict"`
 	Config              datatypes.JSONMap   `json:"config"`
+	ChannelConfig       datatypes.JSONMap   `json:"channel_config"`
 	HeaderRules         []models.HeaderRule `json:"header_rules"`
 	ProxyKeys           string              `json:"proxy_keys"`
 	LastValidatedAt     *time.Time          `json:"last_validated_at"`
@@ -244,6 +249,7 @@ func (s *Server) newGroupResponse(group *models.Group) *GroupResponse {
 		ModelRedirectRules:  group.ModelRedirectRules,
 		ModelRedirectStrict: group.ModelRedirectStrict,
 		Config:              group.Config,
+		ChannelConfig:       group.ChannelConfig,
 		HeaderRules:         headerRules,
 		ProxyKeys:           group.ProxyKeys,
 		LastValidatedAt:     group.LastValidatedAt,
@@ -272,6 +278,8 @@ type ConfigOption struct {
 	Name         string `json:"name"`
 	Description  string `json:"description"`
 	DefaultValue any    `json:"default_value"`
+	MinValue     *int   `json:"min_value,omitempty"`
+	MaxValue     *int   `json:"max_value,omitempty"`
 }
 
 // GetGroupConfigOptions returns a list of available configuration options for groups.
@@ -297,6 +305,8 @@ func (s *Server) GetGroupConfigOptions(c *gin.Context) {
 			Name:         name,
 			Description:  description,
 			DefaultValue: option.DefaultValue,
+			MinValue:     option.MinValue,
+			MaxValue:     option.MaxValue,
 		})
 	}
 
@@ -377,6 +387,16 @@ type UpdateSubGroupWeightRequest struct {
 	Weight int `json:"weight"`
 }
 
+// UpdateSubGroupModelsRequest defines the payload for updating the models a sub group serves
+type UpdateSubGroupModelsRequest struct {
+	Models string `json:"models"`
+}
+
+// UpdateSubGroupPriorityRequest defines the payload for updating a sub group priority
+type UpdateSubGroupPriorityRequest struct {
+	Priority int `json:"priority"`
+}
+
 // GetSubGroups handles getting sub groups of an aggregate group
 func (s *Server) GetSubGroups(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -441,6 +461,60 @@ func (s *Server) UpdateSubGroupWeight(c *gin.Context) {
 	response.SuccessI18n(c, "success.sub_group_weight_updated", nil)
 }
 
+// UpdateSubGroupModels handles updating the models a sub group serves
+func (s *Server) UpdateSubGroupModels(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	subGroupID, err := strconv.Atoi(c.Param("subGroupId"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_sub_group_id")
+		return
+	}
+
+	var req UpdateSubGroupModelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	if err := s.AggregateGroupService.UpdateSubGroupModels(c.Request.Context(), uint(id), uint(subGroupID), req.Models); s.handleGroupError(c, err) {
+		return
+	}
+
+	response.SuccessI18n(c, "success.sub_group_models_updated", nil)
+}
+
+// UpdateSubGroupPriority handles updating the priority of a sub group
+func (s *Server) UpdateSubGroupPriority(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	subGroupID, err := strconv.Atoi(c.Param("subGroupId"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_sub_group_id")
+		return
+	}
+
+	var req UpdateSubGroupPriorityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	if err := s.AggregateGroupService.UpdateSubGroupPriority(c.Request.Context(), uint(id), uint(subGroupID), req.Priority); s.handleGroupError(c, err) {
+		return
+	}
+
+	response.SuccessI18n(c, "success.sub_group_priority_updated", nil)
+}
+
 // DeleteSubGroup handles deleting a sub group from an aggregate group
 func (s *Server) DeleteSubGroup(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
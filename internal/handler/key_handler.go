@@ -5,6 +5,7 @@ import (
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
 	"log"
 	"strconv"
 	"strings"
@@ -133,6 +134,12 @@ func (s *Server) AddMultipleKeysAsync(c *gin.Context) {
 	response.Success(c, taskStatus)
 }
 
+// KeyListResponse is a page of the key list together with the key counts of each tier of the group.
+type KeyListResponse struct {
+	*response.PaginatedResponse
+	TierCounts []services.TierKeyCount `json:"tier_counts"`
+}
+
 // ListKeysInGroup handles listing all keys within a specific group with pagination.
 func (s *Server) ListKeysInGroup(c *gin.Context) {
 	groupID, ok := validateGroupIDFromQuery(c)
@@ -192,9 +199,17 @@ func (s *Server) ListKeysInGroup(c *gin.Context) {
 			keys[i].KeyValue = decryptedValue
 		}
 	}
+	s.KeyService.KeyProvider.LoadCooldowns(keys)
+
 	paginatedResult.Items = keys
 
-	response.Success(c, paginatedResult)
+	tierCounts, err := s.KeyService.CountKeysByTier(groupID)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, KeyListResponse{PaginatedResponse: paginatedResult, TierCounts: tierCounts})
 }
 
 // DeleteMultipleKeys handles deleting keys from a text block within a specific group.
@@ -504,3 +519,113 @@ func (s *Server) UpdateKeyNotes(c *gin.Context) {
 
 	response.Success(c, nil)
 }
+
+// UpdateKeyWeightRequest defines the payload for updating a key's weight.
+type UpdateKeyWeightRequest struct {
+	Weight *int `json:"weight" binding:"required"`
+}
+
+// UpdateKeyWeight handles updating the weight of a specific API key, used by the weighted selection strategy.
+func (s *Server) UpdateKeyWeight(c *gin.Context) {
+	keyIDStr := c.Param("id")
+	keyID, err := strconv.Atoi(keyIDStr)
+	if err != nil || keyID <= 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid key ID format"))
+		return
+	}
+
+	var req UpdateKeyWeightRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	if *req.Weight < 0 || *req.Weight > 1000 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "weight must be between 0 and 1000"))
+		return
+	}
+
+	var key models.APIKey
+	if err := s.DB.First(&key, keyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, app_errors.ErrResourceNotFound)
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+
+	if err := s.KeyService.KeyProvider.UpdateKeyWeight(&key, *req.Weight); err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// maxKeyTier bounds the priority tiers a key can be put in.
+const maxKeyTier = 9
+
+// UpdateKeyTierRequest defines the payload for updating a key's priority tier.
+type UpdateKeyTierRequest struct {
+	Tier *int `json:"tier" binding:"required"`
+}
+
+// UpdateKeyTier handles moving a specific API key to another priority tier. Tier 0 keys are used first;
+// keys of higher tiers are only used when no key of a lower tier is available.
+func (s *Server) UpdateKeyTier(c *gin.Context) {
+	keyIDStr := c.Param("id")
+	keyID, err := strconv.Atoi(keyIDStr)
+	if err != nil || keyID <= 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid key ID format"))
+		return
+	}
+
+	var req UpdateKeyTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	if *req.Tier < 0 || *req.Tier > maxKeyTier {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("tier must be between 0 and %d", maxKeyTier)))
+		return
+	}
+
+	var key models.APIKey
+	if err := s.DB.First(&key, keyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, app_errors.ErrResourceNotFound)
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+
+	if err := s.KeyService.KeyProvider.UpdateKeyTier(&key, *req.Tier); err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// GetKeyCapabilities handles returning the model capability matrix learned for the keys of a group.
+func (s *Server) GetKeyCapabilities(c *gin.Context) {
+	groupID, ok := validateGroupIDFromQuery(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := s.findGroupByID(c, groupID); !ok {
+		return
+	}
+
+	matrix, err := s.KeyService.GetCapabilityMatrix(groupID)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	response.Success(c, matrix)
+}
@@ -0,0 +1,188 @@
+package keypool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// capabilityFieldPrefix prefixes the key HASH fields holding whether the key can use a model: "1" or "0".
+const capabilityFieldPrefix = "model:"
+
+// Known capability of a key for a model.
+const (
+	capabilityUnknown = iota
+	capabilitySupported
+	capabilityUnsupported
+)
+
+// capableKeyCountsKey is the HASH counting, per model, the group's keys known to support it. Refusals are
+// only recorded for a model some key is known to support. The counts are a hint:
+// they can drift under concurrent updates and are rebuilt when the keys are loaded from the database.
+func capableKeyCountsKey(groupID uint) string {
+	return fmt.Sprintf("group:%d:capable_key_counts", groupID)
+}
+
+// keyCapability reads the known capability of a key for the model from its cached details.
+func keyCapability(keyDetails map[string]string, model string) int {
+	if model == "" {
+		return capabilityUnknown
+	}
+	switch keyDetails[capabilityFieldPrefix+model] {
+	case "1":
+		return capabilitySupported
+	case "0":
+		return capabilityUnsupported
+	default:
+		return capabilityUnknown
+	}
+}
+
+// hasCapableKeys reports whether any key of the group is known to support the model.
+func (p *KeyProvider) hasCapableKeys(groupID uint, model string) bool {
+	if model == "" {
+		return false
+	}
+	counts, err := p.store.HGetAll(capableKeyCountsKey(groupID))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": groupID, "error": err}).Warn("Failed to read capable key counts")
+		return false
+	}
+	count, _ := strconv.ParseInt(counts[model], 10, 64)
+	return count > 0
+}
+
+// MarkModelSupported records that the key served a request for the model.
+func (p *KeyProvider) MarkModelSupported(apiKey *models.APIKey, model string) {
+	p.setModelCapability(apiKey, model, true)
+}
+
+// MarkModelUnsupported records that the upstream refused the model for the key. The key stays active
+// for other models; selection just tries it last for this one. Only models the group knows, through its
+// redirect rules or a key known to support them, are recorded, so refusals of names clients make up do
+// not pile up in the store and the database.
+func (p *KeyProvider) MarkModelUnsupported(apiKey *models.APIKey, group *models.Group, model string) {
+	if apiKey == nil || model == "" {
+		return
+	}
+	if _, redirected := group.ModelRedirectMap[model]; !redirected && !p.hasCapableKeys(apiKey.GroupID, model) {
+		return
+	}
+	p.setModelCapability(apiKey, model, false)
+}
+
+// setModelCapability updates the store, so a retry of the same request already sees the change, and the
+// database. Capabilities rarely change, and nothing is written when the capability is unchanged.
+func (p *KeyProvider) setModelCapability(apiKey *models.APIKey, model string, supported bool) {
+	if apiKey == nil || model == "" {
+		return
+	}
+
+	keyHashKey := fmt.Sprintf("key:%d", apiKey.ID)
+	keyDetails, err := p.store.HGetAll(keyHashKey)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Warn("Failed to read key capabilities")
+		return
+	}
+	current := keyCapability(keyDetails, model)
+	if (supported && current == capabilitySupported) || (!supported && current == capabilityUnsupported) {
+		return
+	}
+
+	value := "0"
+	if supported {
+		value = "1"
+	}
+	if err := p.store.HSet(keyHashKey, map[string]any{capabilityFieldPrefix + model: value}); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "model": model, "error": err}).Error("Failed to update key capability in store")
+		return
+	}
+
+	var delta int64
+	if supported {
+		delta = 1
+	} else if current == capabilitySupported {
+		delta = -1
+	}
+	if delta != 0 {
+		if _, err := p.store.HIncrBy(capableKeyCountsKey(apiKey.GroupID), model, delta); err != nil {
+			logrus.WithFields(logrus.Fields{"groupID": apiKey.GroupID, "model": model, "error": err}).Warn("Failed to update capable key count")
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "model": model, "supported": supported}).Info("Learned key model capability")
+
+	capability := models.KeyModelCapability{
+		KeyID:     apiKey.ID,
+		GroupID:   apiKey.GroupID,
+		Model:     model,
+		Supported: supported,
+		UpdatedAt: time.Now(),
+	}
+	err = p.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key_id"}, {Name: "model"}},
+		DoUpdates: clause.AssignmentColumns([]string{"supported", "updated_at"}),
+	}).Create(&capability).Error
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "model": model, "error": err}).Error("Failed to save key capability")
+	}
+}
+
+// loadCapabilities caches the learned capabilities of all keys and rebuilds the capable key counts.
+func (p *KeyProvider) loadCapabilities() error {
+	capableKeyCounts := make(map[uint]map[string]any)
+	var batch []models.KeyModelCapability
+
+	err := p.db.Model(&models.KeyModelCapability{}).
+		Where("key_id IN (?)", p.db.Model(&models.APIKey{}).Select("id")).
+		FindInBatches(&batch, 1000, func(tx *gorm.DB, _ int) error {
+			for _, capability := range batch {
+				value := "0"
+				if capability.Supported {
+					value = "1"
+					if capableKeyCounts[capability.GroupID] == nil {
+						capableKeyCounts[capability.GroupID] = make(map[string]any)
+					}
+					count, _ := capableKeyCounts[capability.GroupID][capability.Model].(int)
+					capableKeyCounts[capability.GroupID][capability.Model] = count + 1
+				}
+				keyHashKey := fmt.Sprintf("key:%d", capability.KeyID)
+				if err := p.store.HSet(keyHashKey, map[string]any{capabilityFieldPrefix + capability.Model: value}); err != nil {
+					logrus.WithFields(logrus.Fields{"keyID": capability.KeyID, "error": err}).Error("Failed to cache key capability")
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to load key capabilities: %w", err)
+	}
+
+	for groupID, counts := range capableKeyCounts {
+		countsKey := capableKeyCountsKey(groupID)
+		p.store.Delete(countsKey)
+		if err := p.store.HSet(countsKey, counts); err != nil {
+			logrus.WithFields(logrus.Fields{"groupID": groupID, "error": err}).Error("Failed to HSet capable key counts for group")
+		}
+	}
+	return nil
+}
+
+// forgetCapabilities drops a removed key from the capable key counts of its group.
+func (p *KeyProvider) forgetCapabilities(groupID uint, keyDetails map[string]string) {
+	for field, value := range keyDetails {
+		model, ok := strings.CutPrefix(field, capabilityFieldPrefix)
+		if !ok || value != "1" {
+			continue
+		}
+		if _, err := p.store.HIncrBy(capableKeyCountsKey(groupID), model, -1); err != nil {
+			logrus.WithFields(logrus.Fields{"groupID": groupID, "model": model, "error": err}).Warn("Failed to update capable key count")
+		}
+	}
+}
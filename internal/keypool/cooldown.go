@@ -0,0 +1,104 @@
+package keypool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// cooldownField is the key HASH field holding the end of a key's cooldown as Unix milliseconds.
+	cooldownField = "cooldown_until"
+	// modelCooldownFieldPrefix prefixes the key HASH fields holding the cooldown of a single model.
+	modelCooldownFieldPrefix = "cooldown_until:"
+)
+
+// CooldownError is returned by SelectKey when every active key of the group is cooling down
+// for the whole key or for the requested model.
+type CooldownError struct {
+	Until time.Time
+	Model string
+}
+
+func (e *CooldownError) Error() string {
+	if e.Model != "" {
+		return fmt.Sprintf("all active keys are rate limited for model %s until %s", e.Model, e.Until.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("all active keys are rate limited until %s", e.Until.Format(time.RFC3339))
+}
+
+// CooldownKey keeps a rate-limited key out of rotation until the given time.
+// With a model, only requests for that model skip the key, since upstream quotas are often per model.
+// Unlike a failure it does not count toward the blacklist threshold, and the key comes back on its
+// own once the time has passed. It is applied synchronously so that a retry of the same request
+// does not pick the key again.
+func (p *KeyProvider) CooldownKey(apiKey *models.APIKey, model string, until time.Time) {
+	field := cooldownField
+	if model != "" {
+		field = modelCooldownFieldPrefix + model
+	}
+
+	keyHashKey := fmt.Sprintf("key:%d", apiKey.ID)
+	if err := p.store.HSet(keyHashKey, map[string]any{field: until.UnixMilli()}); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "model": model, "error": err}).Error("Failed to set key cooldown")
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"keyID": apiKey.ID,
+		"model": model,
+		"until": until.Format(time.RFC3339),
+	}).Debug("Key is cooling down after a rate limit")
+}
+
+// LoadCooldowns sets CooldownUntil and ModelCooldowns on the keys that are currently cooling down.
+func (p *KeyProvider) LoadCooldowns(keys []models.APIKey) {
+	now := time.Now()
+	for i := range keys {
+		keyDetails, err := p.store.HGetAll(fmt.Sprintf("key:%d", keys[i].ID))
+		if err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": keys[i].ID, "error": err}).Warn("Failed to read key cooldown")
+			continue
+		}
+		if until := parseCooldown(keyDetails[cooldownField]); until.After(now) {
+			keys[i].CooldownUntil = &until
+		}
+		for field, value := range keyDetails {
+			model, ok := strings.CutPrefix(field, modelCooldownFieldPrefix)
+			if !ok {
+				continue
+			}
+			if until := parseCooldown(value); until.After(now) {
+				if keys[i].ModelCooldowns == nil {
+					keys[i].ModelCooldowns = make(map[string]time.Time)
+				}
+				keys[i].ModelCooldowns[model] = until
+			}
+		}
+	}
+}
+
+// cooldownUntil returns when the key can serve the model again: the later of its key-wide and
+// model cooldowns, or the zero time when it is not cooling down.
+func cooldownUntil(keyDetails map[string]string, model string) time.Time {
+	until := parseCooldown(keyDetails[cooldownField])
+	if model != "" {
+		if modelUntil := parseCooldown(keyDetails[modelCooldownFieldPrefix+model]); modelUntil.After(until) {
+			until = modelUntil
+		}
+	}
+	return until
+}
+
+// parseCooldown parses a cooldown end stored as Unix milliseconds.
+func parseCooldown(value string) time.Time {
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || millis <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(millis)
+}
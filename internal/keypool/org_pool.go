@@ -0,0 +1,89 @@
+package keypool
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"gpt-load/internal/models"
+)
+
+// Organization pools split the active keys of each tier by organization verification.
+const (
+	poolOrg    = "org"
+	poolNonOrg = "non_org"
+)
+
+// poolActiveKeysKey is the LIST of active keys of one organization pool in one tier of a group.
+func poolActiveKeysKey(groupID uint, tier int, pool string) string {
+	return fmt.Sprintf("group:%d:tier:%d:%s_active_keys", groupID, tier, pool)
+}
+
+func keyPool(isOrg bool) string {
+	if isOrg {
+		return poolOrg
+	}
+	return poolNonOrg
+}
+
+// isOrganizationKey reads the organization verification from cached key details.
+func isOrganizationKey(keyDetails map[string]string) bool {
+	isOrg, _ := strconv.ParseBool(keyDetails["is_organization_key"])
+	return isOrg
+}
+
+// activeKeyLists returns the active key lists to select from, in order. For a premium model routed to
+// organization keys, only the organization pools are returned, and fallback holds the unverified pools
+// for when no tier has any active organization key. A share of the premium requests explores instead:
+// they try the unverified pool of each tier before its organization pool, so keys that gained access
+// get verified by a successful call.
+func (p *KeyProvider) activeKeyLists(group *models.Group, model string) (lists []string, fallback []string, err error) {
+	tiers, err := p.groupTiers(group.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg := group.EffectiveConfig
+	_, isPremium := cfg.PremiumModelsMap[model]
+	if !cfg.PremiumOrgKeysFirst || model == "" || !isPremium {
+		if len(tiers) == 1 {
+			return []string{fmt.Sprintf("group:%d:active_keys", group.ID)}, nil, nil
+		}
+		for _, tier := range tiers {
+			lists = append(lists, tierActiveKeysKey(group.ID, tier))
+		}
+		return lists, nil, nil
+	}
+
+	if rand.Intn(100) < cfg.PremiumExplorationPercent {
+		logrus.WithFields(logrus.Fields{"group": group.Name, "model": model}).Debug("Exploring unverified keys for premium model")
+		for _, tier := range tiers {
+			lists = append(lists, poolActiveKeysKey(group.ID, tier, poolNonOrg), poolActiveKeysKey(group.ID, tier, poolOrg))
+		}
+		return lists, nil, nil
+	}
+
+	for _, tier := range tiers {
+		lists = append(lists, poolActiveKeysKey(group.ID, tier, poolOrg))
+		fallback = append(fallback, poolActiveKeysKey(group.ID, tier, poolNonOrg))
+	}
+	return lists, fallback, nil
+}
+
+// moveKeyToPool moves an active key to the organization pool matching its new verification status.
+func (p *KeyProvider) moveKeyToPool(keyID uint, isOrg bool) error {
+	keyDetails, err := p.store.HGetAll(fmt.Sprintf("key:%d", keyID))
+	if err != nil {
+		return fmt.Errorf("failed to get key details for key %d: %w", keyID, err)
+	}
+	if keyDetails["status"] != models.KeyStatusActive {
+		return nil
+	}
+	groupID, err := strconv.ParseUint(keyDetails["group_id"], 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse group ID of key %d: %w", keyID, err)
+	}
+	return p.activateKey(uint(groupID), keyID, keyTier(keyDetails), isOrg)
+}
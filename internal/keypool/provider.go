@@ -1,8 +1,8 @@
 package keypool
 
 import (
-	"errors"
 	"fmt"
+	"gpt-load/internal/channel"
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
@@ -11,6 +11,7 @@ import (
 	"math/rand"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -22,6 +23,7 @@ type KeyProvider struct {
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
 	encryptionSvc   encryption.Service
+	leases          sync.Map // *models.APIKey -> *keyLease held by this node
 }
 
 // NewProvider 创建一个新的 KeyProvider 实例。
@@ -34,32 +36,154 @@ func NewProvider(db *gorm.DB, store store.Store, settingsManager *config.SystemS
 	}
 }
 
-// SelectKey 为指定的分组原子性地选择并轮换一个可用的 APIKey。
-func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
-	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
+// SelectKey 为指定的分组选择一个可用的 APIKey，选择方式由分组的 key_selection_strategy 决定。
+// Tiers are tried lowest first: backup keys are only used when every key of the lower tiers is blacklisted or cooling down.
+// Keys cooling down after a rate limit, for the whole key or for the requested model, are skipped until their cooldown ends,
+// and keys at the group's max_concurrency_per_key are skipped until a request on them finishes.
+// Within a tier, keys the upstream refused the model for are only used when no other key is usable.
+// With premium_org_keys_first, premium models only use organization-verified keys, unless the group has no
+// active organization key or the request is picked for exploration.
+// The caller must release the key with ReleaseKey once the request is done.
+func (p *KeyProvider) SelectKey(group *models.Group, model string) (*models.APIKey, error) {
+	activeKeysListKeys, fallbackListKeys, err := p.activeKeyLists(group, model)
+	if err != nil {
+		return nil, err
+	}
 
-	// 1. Atomically rotate the key ID from the list
-	keyIDStr, err := p.store.Rotate(activeKeysListKey)
+	apiKey, skipped, err := p.selectKeyFromLists(group, activeKeysListKeys, model)
 	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			return nil, app_errors.ErrNoActiveKeys
+		return nil, err
+	}
+	// Unverified keys only stand in for organization keys when there is no organization key to wait for.
+	if apiKey == nil && !skipped.saturated && skipped.cooldownEnd.IsZero() && len(fallbackListKeys) > 0 {
+		logrus.WithFields(logrus.Fields{"group": group.Name, "model": model}).Debug("No active organization key for premium model, using unverified keys")
+		apiKey, skipped, err = p.selectKeyFromLists(group, fallbackListKeys, model)
+		if err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("failed to rotate key from store: %w", err)
+	}
+	if apiKey != nil {
+		return apiKey, nil
+	}
+	if skipped.saturated {
+		return nil, app_errors.ErrKeysSaturated
 	}
 
-	keyID, err := strconv.ParseUint(keyIDStr, 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse key ID '%s': %w", keyIDStr, err)
+	// Every active key has been considered and all of them are cooling down.
+	if skipped.cooldownEnd.IsZero() {
+		return nil, app_errors.ErrNoActiveKeys
 	}
+	return nil, &CooldownError{Until: skipped.cooldownEnd, Model: model}
+}
 
-	// 2. Get key details from HASH
-	keyHashKey := fmt.Sprintf("key:%d", keyID)
-	keyDetails, err := p.store.HGetAll(keyHashKey)
+// selectKeyFromLists tries the active key lists in order and leases the first usable key.
+func (p *KeyProvider) selectKeyFromLists(group *models.Group, activeKeysListKeys []string, model string) (*models.APIKey, skippedKeys, error) {
+	var earliest skippedKeys
+	for _, activeKeysListKey := range activeKeysListKeys {
+		apiKey, skipped, err := p.selectKeyFromList(group, activeKeysListKey, model)
+		if err != nil {
+			return nil, earliest, err
+		}
+		if apiKey != nil {
+			return apiKey, earliest, nil
+		}
+		// Saturated keys free up as soon as a request finishes, so the next tier is not used for them.
+		if skipped.saturated {
+			earliest.saturated = true
+			return nil, earliest, nil
+		}
+		if !skipped.cooldownEnd.IsZero() && (earliest.cooldownEnd.IsZero() || skipped.cooldownEnd.Before(earliest.cooldownEnd)) {
+			earliest.cooldownEnd = skipped.cooldownEnd
+		}
+	}
+	return nil, earliest, nil
+}
+
+// skippedKeys tells why no key of an active key list could be selected.
+type skippedKeys struct {
+	cooldownEnd time.Time // Earliest end of the cooldowns skipped, zero when no key was cooling down
+	saturated   bool      // Some keys were at their concurrency limit
+}
+
+// keyCandidate is a usable key held back while looking for a key the upstream has not refused the model for.
+type keyCandidate struct {
+	keyID      uint
+	keyDetails map[string]string
+}
+
+// selectKeyFromList leases the first key of an active key list that is neither cooling down nor saturated.
+// Keys the upstream refused the model for come last; keys never tried with it stay in the normal order.
+func (p *KeyProvider) selectKeyFromList(group *models.Group, activeKeysListKey string, model string) (*models.APIKey, skippedKeys, error) {
+	var skipped skippedKeys
+	next, err := p.keyCandidates(group.ID, activeKeysListKey, group.EffectiveConfig.KeySelectionStrategy)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get key details for key ID %d: %w", keyID, err)
+		return nil, skipped, err
+	}
+
+	tryLease := func(keyID uint, keyDetails map[string]string) *models.APIKey {
+		lease := p.acquireKey(group.ID, keyID, group.EffectiveConfig.MaxConcurrencyPerKey)
+		if lease == nil {
+			skipped.saturated = true
+			return nil
+		}
+		apiKey := p.apiKeyFromDetails(keyID, group.ID, keyDetails)
+		p.leases.Store(apiKey, lease)
+		return apiKey
+	}
+
+	now := time.Now()
+	var refused []keyCandidate
+	seen := make(map[string]bool)
+	for {
+		keyIDStr, ok, err := next()
+		if err != nil {
+			return nil, skipped, err
+		}
+		if !ok || seen[keyIDStr] {
+			break
+		}
+		seen[keyIDStr] = true
+
+		keyID, err := strconv.ParseUint(keyIDStr, 10, 64)
+		if err != nil {
+			return nil, skipped, fmt.Errorf("failed to parse key ID '%s': %w", keyIDStr, err)
+		}
+
+		// Get key details from HASH
+		keyHashKey := fmt.Sprintf("key:%d", keyID)
+		keyDetails, err := p.store.HGetAll(keyHashKey)
+		if err != nil {
+			return nil, skipped, fmt.Errorf("failed to get key details for key ID %d: %w", keyID, err)
+		}
+
+		if until := cooldownUntil(keyDetails, model); until.After(now) {
+			if skipped.cooldownEnd.IsZero() || until.Before(skipped.cooldownEnd) {
+				skipped.cooldownEnd = until
+			}
+			continue
+		}
+
+		if keyCapability(keyDetails, model) == capabilityUnsupported {
+			refused = append(refused, keyCandidate{uint(keyID), keyDetails})
+			continue
+		}
+
+		if apiKey := tryLease(uint(keyID), keyDetails); apiKey != nil {
+			return apiKey, skipped, nil
+		}
 	}
 
-	// 3. Manually unmarshal the map into an APIKey struct
+	for _, candidate := range refused {
+		if apiKey := tryLease(candidate.keyID, candidate.keyDetails); apiKey != nil {
+			return apiKey, skipped, nil
+		}
+	}
+	return nil, skipped, nil
+}
+
+// apiKeyFromDetails builds an APIKey from its cached HASH fields.
+func (p *KeyProvider) apiKeyFromDetails(keyID uint, groupID uint, keyDetails map[string]string) *models.APIKey {
+	// Manually unmarshal the map into an APIKey struct
 	failureCount, _ := strconv.ParseInt(keyDetails["failure_count"], 10, 64)
 	createdAt, _ := strconv.ParseInt(keyDetails["created_at"], 10, 64)
 
@@ -75,16 +199,17 @@ func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
 		decryptedKeyValue = encryptedKeyValue
 	}
 
-	apiKey := &models.APIKey{
-		ID:           uint(keyID),
-		KeyValue:     decryptedKeyValue,
-		Status:       keyDetails["status"],
-		FailureCount: failureCount,
-		GroupID:      groupID,
-		CreatedAt:    time.Unix(createdAt, 0),
+	return &models.APIKey{
+		ID:                keyID,
+		KeyValue:          decryptedKeyValue,
+		Status:            keyDetails["status"],
+		FailureCount:      failureCount,
+		Tier:              keyTier(keyDetails),
+		GroupID:           groupID,
+		IsOrganizationKey: isOrganizationKey(keyDetails),
+		OrganizationID:    keyDetails["organization_id"],
+		CreatedAt:         time.Unix(createdAt, 0),
 	}
-
-	return apiKey, nil
 }
 
 // UpdateStatus 异步地提交一个 Key 状态更新任务。
@@ -96,10 +221,9 @@ func (p *KeyProvider) UpdateStatus(apiKey *models.APIKey, group *models.Group, i
 func (p *KeyProvider) UpdateStatusWithOptions(apiKey *models.APIKey, group *models.Group, isSuccess bool, errorMessage string, forceBlacklist bool) {
 	go func() {
 		keyHashKey := fmt.Sprintf("key:%d", apiKey.ID)
-		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", group.ID)
 
 		if isSuccess {
-			if err := p.handleSuccess(apiKey, keyHashKey, activeKeysListKey); err != nil {
+			if err := p.handleSuccess(apiKey, group.ID, keyHashKey); err != nil {
 				logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to handle key success")
 			}
 		} else {
@@ -109,7 +233,7 @@ func (p *KeyProvider) UpdateStatusWithOptions(apiKey *models.APIKey, group *mode
 					"error": errorMessage,
 				}).Debug("Uncounted error, skipping failure handling")
 			} else {
-				if err := p.handleFailure(apiKey, group, keyHashKey, activeKeysListKey, forceBlacklist); err != nil {
+				if err := p.handleFailure(apiKey, group, keyHashKey, forceBlacklist); err != nil {
 					logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to handle key failure")
 				}
 			}
@@ -144,7 +268,7 @@ func (p *KeyProvider) executeTransactionWithRetry(operation func(tx *gorm.DB) er
 	return err
 }
 
-func (p *KeyProvider) handleSuccess(apiKey *models.APIKey, keyHashKey, activeKeysListKey string) error {
+func (p *KeyProvider) handleSuccess(apiKey *models.APIKey, groupID uint, keyHashKey string) error {
 	keyDetails, err := p.store.HGetAll(keyHashKey)
 	if err != nil {
 		return fmt.Errorf("failed to get key details from store: %w", err)
@@ -185,11 +309,8 @@ func (p *KeyProvider) handleSuccess(apiKey *models.APIKey, keyHashKey, activeKey
 
 		if !isActive {
 			logrus.WithField("keyID", apiKey.ID).Debug("Key has recovered and is being restored to active pool.")
-			if err := p.store.LRem(activeKeysListKey, 0, apiKey.ID); err != nil {
-				return fmt.Errorf("failed to LRem key before LPush on recovery: %w", err)
-			}
-			if err := p.store.LPush(activeKeysListKey, apiKey.ID); err != nil {
-				return fmt.Errorf("failed to LPush key back to active list: %w", err)
+			if err := p.activateKey(groupID, apiKey.ID, keyTier(keyDetails), isOrganizationKey(keyDetails) || apiKey.IsOrganizationKey); err != nil {
+				return fmt.Errorf("failed to push key back to active list: %w", err)
 			}
 		}
 
@@ -230,6 +351,9 @@ func (p *KeyProvider) UpdateOrganizationInfo(apiKey *models.APIKey, organization
 		if err := p.store.HSet(keyHashKey, updates); err != nil {
 			return fmt.Errorf("failed to update organization info in store: %w", err)
 		}
+		if err := p.moveKeyToPool(apiKey.ID, true); err != nil {
+			return fmt.Errorf("failed to move key to organization pool: %w", err)
+		}
 
 		logrus.WithFields(logrus.Fields{
 			"keyID":           apiKey.ID,
@@ -273,6 +397,9 @@ func (p *KeyProvider) ClearOrganizationInfo(apiKey *models.APIKey) error {
 		if err := p.store.HSet(keyHashKey, updates); err != nil {
 			return fmt.Errorf("failed to clear organization info in store: %w", err)
 		}
+		if err := p.moveKeyToPool(apiKey.ID, false); err != nil {
+			return fmt.Errorf("failed to move key out of organization pool: %w", err)
+		}
 
 		logrus.WithFields(logrus.Fields{
 			"keyID": apiKey.ID,
@@ -282,7 +409,7 @@ func (p *KeyProvider) ClearOrganizationInfo(apiKey *models.APIKey) error {
 	})
 }
 
-func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group, keyHashKey, activeKeysListKey string, forceBlacklist bool) error {
+func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group, keyHashKey string, forceBlacklist bool) error {
 	keyDetails, err := p.store.HGetAll(keyHashKey)
 	if err != nil {
 		return fmt.Errorf("failed to get key details from store: %w", err)
@@ -321,8 +448,8 @@ func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group,
 
 		if shouldBlacklist {
 			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "threshold": blacklistThreshold}).Warn("Key has reached blacklist threshold, disabling.")
-			if err := p.store.LRem(activeKeysListKey, 0, apiKey.ID); err != nil {
-				return fmt.Errorf("failed to LRem key from active list: %w", err)
+			if err := p.deactivateKey(group.ID, apiKey.ID, keyTier(keyDetails)); err != nil {
+				return fmt.Errorf("failed to remove key from active list: %w", err)
 			}
 			if err := p.store.HSet(keyHashKey, map[string]any{"status": models.KeyStatusInvalid}); err != nil {
 				return fmt.Errorf("failed to update key status to invalid in store: %w", err)
@@ -339,6 +466,9 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 
 	// 1. 分批从数据库加载并使用 Pipeline 写入 Redis
 	allActiveKeyIDs := make(map[uint][]any)
+	allTierActiveKeyIDs := make(map[uint]map[int][]any)
+	allPoolActiveKeyIDs := make(map[uint]map[string][]any)
+	allKeyWeights := make(map[uint]map[string]any)
 	batchSize := 1000
 	var batchKeys []*models.APIKey
 
@@ -362,9 +492,24 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 				}
 			}
 
+			if allTierActiveKeyIDs[key.GroupID] == nil {
+				allTierActiveKeyIDs[key.GroupID] = make(map[int][]any)
+			}
 			if key.Status == models.KeyStatusActive {
 				allActiveKeyIDs[key.GroupID] = append(allActiveKeyIDs[key.GroupID], key.ID)
+				allTierActiveKeyIDs[key.GroupID][key.Tier] = append(allTierActiveKeyIDs[key.GroupID][key.Tier], key.ID)
+				if allPoolActiveKeyIDs[key.GroupID] == nil {
+					allPoolActiveKeyIDs[key.GroupID] = make(map[string][]any)
+				}
+				poolListKey := poolActiveKeysKey(key.GroupID, key.Tier, keyPool(key.IsOrganizationKey))
+				allPoolActiveKeyIDs[key.GroupID][poolListKey] = append(allPoolActiveKeyIDs[key.GroupID][poolListKey], key.ID)
+			} else if _, ok := allTierActiveKeyIDs[key.GroupID][key.Tier]; !ok {
+				allTierActiveKeyIDs[key.GroupID][key.Tier] = nil
 			}
+			if allKeyWeights[key.GroupID] == nil {
+				allKeyWeights[key.GroupID] = make(map[string]any)
+			}
+			allKeyWeights[key.GroupID][keyStatField(key.ID, statWeight)] = key.Weight
 		}
 
 		if pipeline != nil {
@@ -391,6 +536,45 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 		}
 	}
 
+	// 3. 更新所有分组的分层及组织池 active_keys 列表
+	for groupID, tierActiveIDs := range allTierActiveKeyIDs {
+		tiers := make([]int, 0, len(tierActiveIDs))
+		for tier, activeIDs := range tierActiveIDs {
+			tiers = append(tiers, tier)
+			for _, listKey := range tierListKeys(groupID, tier) {
+				p.store.Delete(listKey)
+			}
+			if len(activeIDs) == 0 {
+				continue
+			}
+			if err := p.store.LPush(tierActiveKeysKey(groupID, tier), activeIDs...); err != nil {
+				logrus.WithFields(logrus.Fields{"groupID": groupID, "tier": tier, "error": err}).Error("Failed to LPush tier active keys for group")
+			}
+		}
+		for poolListKey, activeIDs := range allPoolActiveKeyIDs[groupID] {
+			if err := p.store.LPush(poolListKey, activeIDs...); err != nil {
+				logrus.WithFields(logrus.Fields{"groupID": groupID, "list": poolListKey, "error": err}).Error("Failed to LPush organization pool active keys for group")
+			}
+		}
+		if err := p.setGroupTiers(groupID, tiers); err != nil {
+			logrus.WithFields(logrus.Fields{"groupID": groupID, "error": err}).Error("Failed to set key tiers for group")
+		}
+	}
+
+	// 4. 缓存已学习的密钥模型能力
+	if err := p.loadCapabilities(); err != nil {
+		logrus.WithError(err).Error("Failed to load key capabilities")
+	}
+
+	// 5. 重建密钥选择统计，进行中计数在下次租用密钥时按未过期的租约重新计算
+	for groupID, weights := range allKeyWeights {
+		statsKey := keyStatsKey(groupID)
+		p.store.Delete(statsKey)
+		if err := p.store.HSet(statsKey, weights); err != nil {
+			logrus.WithFields(logrus.Fields{"groupID": groupID, "error": err}).Error("Failed to HSet key stats for group")
+		}
+	}
+
 	return nil
 }
 
@@ -453,6 +637,9 @@ func (p *KeyProvider) RemoveKeys(groupID uint, keyValues []string) (int64, error
 		if result.Error != nil {
 			return result.Error
 		}
+		if err := tx.Where("key_id IN ?", keyIDsToDelete).Delete(&models.KeyModelCapability{}).Error; err != nil {
+			return err
+		}
 		deletedCount = result.RowsAffected
 
 		for _, key := range keysToDelete {
@@ -601,6 +788,9 @@ func (p *KeyProvider) removeKeysByStatus(groupID uint, status ...string) (int64,
 		if result.Error != nil {
 			return result.Error
 		}
+		if err := tx.Where("key_id IN ?", pluckIDs(keysToRemove)).Delete(&models.KeyModelCapability{}).Error; err != nil {
+			return err
+		}
 		removedCount = result.RowsAffected
 
 		for _, key := range keysToRemove {
@@ -632,16 +822,56 @@ func (p *KeyProvider) RemoveKeysFromStore(groupID uint, keyIDs []uint) error {
 		}).Error("Failed to delete active keys list")
 		return err
 	}
+	if tiers, err := p.groupTiers(groupID); err == nil {
+		for _, tier := range tiers {
+			for _, listKey := range tierListKeys(groupID, tier) {
+				if err := p.store.Delete(listKey); err != nil {
+					logrus.WithFields(logrus.Fields{
+						"groupID": groupID,
+						"list":    listKey,
+						"error":   err,
+					}).Error("Failed to delete tier active keys list")
+				}
+			}
+		}
+	}
+	if err := p.store.Delete(capableKeyCountsKey(groupID)); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"groupID": groupID,
+			"error":   err,
+		}).Error("Failed to delete capable key counts")
+	}
+	if err := p.store.Delete(keyTiersKey(groupID)); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"groupID": groupID,
+			"error":   err,
+		}).Error("Failed to delete key tiers")
+	}
+	if err := p.store.Delete(keyStatsKey(groupID)); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"groupID": groupID,
+			"error":   err,
+		}).Error("Failed to delete key stats")
+	}
 
 	// 第二步：批量删除所有相关的key hash
 	for _, keyID := range keyIDs {
 		keyHashKey := fmt.Sprintf("key:%d", keyID)
+		if keyDetails, err := p.store.HGetAll(keyHashKey); err == nil {
+			p.forgetKeyCredentials(keyDetails)
+		}
 		if err := p.store.Delete(keyHashKey); err != nil {
 			logrus.WithFields(logrus.Fields{
 				"keyID": keyID,
 				"error": err,
 			}).Error("Failed to delete key hash")
 		}
+		if err := p.store.Delete(keyLeasesKey(keyID)); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"keyID": keyID,
+				"error": err,
+			}).Error("Failed to delete key leases")
+		}
 	}
 
 	logrus.WithFields(logrus.Fields{
@@ -661,14 +891,18 @@ func (p *KeyProvider) addKeyToStore(key *models.APIKey) error {
 		return fmt.Errorf("failed to HSet key details for key %d: %w", key.ID, err)
 	}
 
-	// 2. If active, add to the active LIST
+	if err := p.store.HSet(keyStatsKey(key.GroupID), map[string]any{keyStatField(key.ID, statWeight): key.Weight}); err != nil {
+		return fmt.Errorf("failed to HSet weight for key %d: %w", key.ID, err)
+	}
+
+	if err := p.addGroupTier(key.GroupID, key.Tier); err != nil {
+		return fmt.Errorf("failed to record tier %d for group %d: %w", key.Tier, key.GroupID, err)
+	}
+
+	// 2. If active, add to the active LISTs
 	if key.Status == models.KeyStatusActive {
-		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", key.GroupID)
-		if err := p.store.LRem(activeKeysListKey, 0, key.ID); err != nil {
-			return fmt.Errorf("failed to LRem key %d before LPush for group %d: %w", key.ID, key.GroupID, err)
-		}
-		if err := p.store.LPush(activeKeysListKey, key.ID); err != nil {
-			return fmt.Errorf("failed to LPush key %d to group %d: %w", key.ID, key.GroupID, err)
+		if err := p.activateKey(key.GroupID, key.ID, key.Tier, key.IsOrganizationKey); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -676,27 +910,56 @@ func (p *KeyProvider) addKeyToStore(key *models.APIKey) error {
 
 // removeKeyFromStore is a helper to remove a single key from the cache.
 func (p *KeyProvider) removeKeyFromStore(keyID, groupID uint) error {
-	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
-	if err := p.store.LRem(activeKeysListKey, 0, keyID); err != nil {
+	keyHashKey := fmt.Sprintf("key:%d", keyID)
+	keyDetails, err := p.store.HGetAll(keyHashKey)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Error("Failed to get key details before removal")
+	}
+	if err := p.deactivateKey(groupID, keyID, keyTier(keyDetails)); err != nil {
 		logrus.WithFields(logrus.Fields{"keyID": keyID, "groupID": groupID, "error": err}).Error("Failed to LRem key from active list")
 	}
+	p.forgetCapabilities(groupID, keyDetails)
+	p.forgetKeyCredentials(keyDetails)
+
+	if err := p.store.HDel(keyStatsKey(groupID), keyStatField(keyID, statLastUsed), keyStatField(keyID, statInFlight), keyStatField(keyID, statWeight)); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "groupID": groupID, "error": err}).Error("Failed to HDel key stats")
+	}
+
+	if err := p.store.Delete(keyLeasesKey(keyID)); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Error("Failed to delete key leases")
+	}
 
-	keyHashKey := fmt.Sprintf("key:%d", keyID)
 	if err := p.store.Delete(keyHashKey); err != nil {
 		return fmt.Errorf("failed to delete key HASH for key %d: %w", keyID, err)
 	}
 	return nil
 }
 
+// forgetKeyCredentials drops the credentials channels cached for a removed key, such as Vertex AI access tokens.
+func (p *KeyProvider) forgetKeyCredentials(keyDetails map[string]string) {
+	encryptedKeyValue := keyDetails["key_string"]
+	if encryptedKeyValue == "" {
+		return
+	}
+	keyValue, err := p.encryptionSvc.Decrypt(encryptedKeyValue)
+	if err != nil {
+		keyValue = encryptedKeyValue
+	}
+	channel.ForgetKeyCredentials(keyValue)
+}
+
 // apiKeyToMap converts an APIKey model to a map for HSET.
 func (p *KeyProvider) apiKeyToMap(key *models.APIKey) map[string]any {
 	return map[string]any{
-		"id":            fmt.Sprint(key.ID),
-		"key_string":    key.KeyValue,
-		"status":        key.Status,
-		"failure_count": key.FailureCount,
-		"group_id":      key.GroupID,
-		"created_at":    key.CreatedAt.Unix(),
+		"id":                  fmt.Sprint(key.ID),
+		"key_string":          key.KeyValue,
+		"status":              key.Status,
+		"failure_count":       key.FailureCount,
+		"tier":                key.Tier,
+		"group_id":            key.GroupID,
+		"is_organization_key": key.IsOrganizationKey,
+		"organization_id":     key.OrganizationID,
+		"created_at":          key.CreatedAt.Unix(),
 	}
 }
 
@@ -724,6 +987,9 @@ func (p *KeyProvider) UpdateOrganizationStatus(keyID uint, isOrganizationKey boo
 	if err := p.store.HSet(keyHashKey, map[string]any{"is_organization_key": isOrganizationKey}); err != nil {
 		logrus.WithError(err).WithField("key_id", keyID).Warn("Failed to update organization status in cache")
 	}
+	if err := p.moveKeyToPool(keyID, isOrganizationKey); err != nil {
+		logrus.WithError(err).WithField("key_id", keyID).Warn("Failed to move key to its organization pool")
+	}
 
 	return nil
 }
@@ -0,0 +1,273 @@
+package keypool
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Key selection strategies, set per group by key_selection_strategy.
+const (
+	SelectionRoundRobin    = "round_robin"
+	SelectionRandom        = "random"
+	SelectionLeastRecent   = "lru"
+	SelectionLeastInFlight = "least_inflight"
+	SelectionWeighted      = "weighted"
+)
+
+// Fields of the per-group key stats HASH, stored as "<key id>:<stat>".
+const (
+	statLastUsed = "last_used"
+	statInFlight = "in_flight"
+	statWeight   = "weight"
+)
+
+// keyStatsKey is the HASH holding the selection stats of a group's keys. Keeping them in one HASH
+// lets a selection read the stats of every key with a single store call.
+func keyStatsKey(groupID uint) string {
+	return fmt.Sprintf("group:%d:key_stats", groupID)
+}
+
+func keyStatField(keyID any, stat string) string {
+	return fmt.Sprintf("%v:%s", keyID, stat)
+}
+
+// keyCandidates returns an iterator over the key IDs of an active key list in the order the strategy prefers them.
+// Round robin rotates the shared list one key at a time; the other strategies rank a snapshot of the list
+// using the shared key stats, so every node sees the same usage.
+func (p *KeyProvider) keyCandidates(groupID uint, activeKeysListKey string, strategy string) (func() (string, bool, error), error) {
+	if strategy == "" || strategy == SelectionRoundRobin {
+		return func() (string, bool, error) {
+			keyIDStr, err := p.store.Rotate(activeKeysListKey)
+			if errors.Is(err, store.ErrNotFound) {
+				return "", false, nil
+			}
+			if err != nil {
+				return "", false, fmt.Errorf("failed to rotate key from store: %w", err)
+			}
+			return keyIDStr, true, nil
+		}, nil
+	}
+
+	keyIDs, err := p.store.LRange(activeKeysListKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read active keys from store: %w", err)
+	}
+	var stats map[string]string
+	if len(keyIDs) > 0 {
+		if stats, err = p.store.HGetAll(keyStatsKey(groupID)); err != nil {
+			return nil, fmt.Errorf("failed to read key stats from store: %w", err)
+		}
+		if strategy == SelectionLeastInFlight {
+			p.refreshInFlight(groupID, keyIDs, stats)
+		}
+	}
+
+	ranked := rankKeys(keyIDs, stats, strategy)
+	return func() (string, bool, error) {
+		if len(ranked) == 0 {
+			return "", false, nil
+		}
+		keyIDStr := ranked[0]
+		ranked = ranked[1:]
+		return keyIDStr, true, nil
+	}, nil
+}
+
+// refreshInFlight replaces the recorded in-flight counts with the live lease counts of the keys. The recorded
+// count of a key is only written when it is leased or released, so it goes stale once its leases expire.
+func (p *KeyProvider) refreshInFlight(groupID uint, keyIDs []string, stats map[string]string) {
+	now := float64(time.Now().UnixMilli())
+	refreshed := make(map[string]any, len(keyIDs))
+	for _, keyIDStr := range keyIDs {
+		keyID, err := strconv.ParseUint(keyIDStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		leasesKey := keyLeasesKey(uint(keyID))
+		if err := p.store.ZRemRangeByScore(leasesKey, math.Inf(-1), now); err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Warn("Failed to prune expired leases of key")
+		}
+		count, err := p.store.ZCard(leasesKey)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Warn("Failed to count in-flight requests for key")
+			continue
+		}
+		field := keyStatField(keyIDStr, statInFlight)
+		if stats[field] != strconv.FormatInt(count, 10) {
+			refreshed[field] = count
+		}
+		stats[field] = strconv.FormatInt(count, 10)
+	}
+	if len(refreshed) == 0 {
+		return
+	}
+	if err := p.store.HSet(keyStatsKey(groupID), refreshed); err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": groupID, "error": err}).Warn("Failed to update in-flight counts of keys")
+	}
+}
+
+// rankKeys orders key IDs by preference for the strategy. Keys start in random order, so ties are broken randomly.
+func rankKeys(keyIDs []string, stats map[string]string, strategy string) []string {
+	ranked := make([]string, len(keyIDs))
+	copy(ranked, keyIDs)
+	rand.Shuffle(len(ranked), func(i, j int) { ranked[i], ranked[j] = ranked[j], ranked[i] })
+
+	stat := func(keyID, name string, fallback int64) int64 {
+		value, err := strconv.ParseInt(stats[keyStatField(keyID, name)], 10, 64)
+		if err != nil {
+			return fallback
+		}
+		return value
+	}
+
+	switch strategy {
+	case SelectionLeastRecent:
+		sortKeysBy(ranked, func(keyID string) float64 { return float64(stat(keyID, statLastUsed, 0)) })
+	case SelectionLeastInFlight:
+		sortKeysBy(ranked, func(keyID string) float64 { return float64(stat(keyID, statInFlight, 0)) })
+	case SelectionWeighted:
+		// Weighted random order (Efraimidis-Spirakis): each key draws -ln(u)/weight and the smallest goes first,
+		// so a key comes first with probability proportional to its weight. Keys with no weight come last.
+		sortKeysBy(ranked, func(keyID string) float64 {
+			weight := stat(keyID, statWeight, 1)
+			if weight <= 0 {
+				return math.Inf(1)
+			}
+			return -math.Log(1-rand.Float64()) / float64(weight)
+		})
+	case SelectionRandom:
+	default:
+		logrus.Warnf("Unknown key selection strategy %q, using random order", strategy)
+	}
+	return ranked
+}
+
+// sortKeysBy stably sorts key IDs by ascending score, computing each score once.
+func sortKeysBy(keyIDs []string, score func(keyID string) float64) {
+	scores := make(map[string]float64, len(keyIDs))
+	for _, keyID := range keyIDs {
+		scores[keyID] = score(keyID)
+	}
+	sort.SliceStable(keyIDs, func(i, j int) bool { return scores[keyIDs[i]] < scores[keyIDs[j]] })
+}
+
+// leaseTTL is how long an in-flight lease outlives its last renewal. Leases of a node that stopped without
+// releasing them expire after it, so its requests no longer count against the concurrency limit.
+const leaseTTL = time.Minute
+
+// keyLeasesKey is the sorted set of the in-flight leases on a key, scored by their deadline in Unix milliseconds.
+func keyLeasesKey(keyID uint) string {
+	return fmt.Sprintf("key:%d:leases", keyID)
+}
+
+// keyLease is an in-flight lease held by this node. It is renewed until the request is released.
+type keyLease struct {
+	groupID uint
+	keyID   uint
+	member  string
+	stop    chan struct{}
+}
+
+// acquireKey takes a lease on the key for a request. The live lease count and last use feed the
+// least_inflight and lru strategies. With a concurrency limit, the lease is added first and dropped
+// again when it went over the limit, so selections on different nodes cannot both take the last slot.
+// It returns nil when the key is saturated.
+func (p *KeyProvider) acquireKey(groupID, keyID uint, maxConcurrency int) *keyLease {
+	lease := &keyLease{groupID: groupID, keyID: keyID, member: uuid.NewString(), stop: make(chan struct{})}
+	leasesKey := keyLeasesKey(keyID)
+	now := time.Now()
+	if err := p.store.ZRemRangeByScore(leasesKey, math.Inf(-1), float64(now.UnixMilli())); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Warn("Failed to prune expired leases of key")
+	}
+	if err := p.store.ZAdd(leasesKey, float64(now.Add(leaseTTL).UnixMilli()), lease.member); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Warn("Failed to lease key")
+		return lease
+	}
+
+	count, err := p.store.ZCard(leasesKey)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Warn("Failed to count in-flight requests for key")
+	} else if maxConcurrency > 0 && count > int64(maxConcurrency) {
+		if err := p.store.ZRem(leasesKey, lease.member); err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Warn("Failed to drop lease of saturated key")
+		}
+		return nil
+	}
+
+	stats := map[string]any{keyStatField(keyID, statLastUsed): now.UnixMilli()}
+	if err == nil {
+		stats[keyStatField(keyID, statInFlight)] = count
+	}
+	if err := p.store.HSet(keyStatsKey(groupID), stats); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Warn("Failed to record last use of key")
+	}
+	go p.renewLease(lease)
+	return lease
+}
+
+// renewLease pushes the deadline of a lease back while its request is running, so long streams keep it.
+func (p *KeyProvider) renewLease(lease *keyLease) {
+	ticker := time.NewTicker(leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-lease.stop:
+			return
+		case <-ticker.C:
+			deadline := time.Now().Add(leaseTTL).UnixMilli()
+			if err := p.store.ZAdd(keyLeasesKey(lease.keyID), float64(deadline), lease.member); err != nil {
+				logrus.WithFields(logrus.Fields{"keyID": lease.keyID, "error": err}).Warn("Failed to renew lease of key")
+			}
+		}
+	}
+}
+
+// ReleaseKey ends the lease taken by SelectKey. It must be called once the request using the key
+// has finished, including any streaming to the client.
+func (p *KeyProvider) ReleaseKey(apiKey *models.APIKey) {
+	if apiKey == nil {
+		return
+	}
+	value, ok := p.leases.LoadAndDelete(apiKey)
+	if !ok {
+		return
+	}
+	lease := value.(*keyLease)
+	close(lease.stop)
+
+	leasesKey := keyLeasesKey(lease.keyID)
+	if err := p.store.ZRem(leasesKey, lease.member); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": lease.keyID, "error": err}).Warn("Failed to release lease of key")
+		return
+	}
+	count, err := p.store.ZCard(leasesKey)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": lease.keyID, "error": err}).Warn("Failed to count in-flight requests for key")
+		return
+	}
+	if err := p.store.HSet(keyStatsKey(lease.groupID), map[string]any{keyStatField(lease.keyID, statInFlight): count}); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": lease.keyID, "error": err}).Warn("Failed to update in-flight count for key")
+	}
+}
+
+// UpdateKeyWeight sets the weight used by the weighted selection strategy.
+func (p *KeyProvider) UpdateKeyWeight(apiKey *models.APIKey, weight int) error {
+	if err := p.db.Model(apiKey).Update("weight", weight).Error; err != nil {
+		return fmt.Errorf("failed to update weight for key %d: %w", apiKey.ID, err)
+	}
+	if err := p.store.HSet(keyStatsKey(apiKey.GroupID), map[string]any{keyStatField(apiKey.ID, statWeight): weight}); err != nil {
+		return fmt.Errorf("failed to update weight for key %d in store: %w", apiKey.ID, err)
+	}
+	return nil
+}
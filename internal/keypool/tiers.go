@@ -0,0 +1,160 @@
+package keypool
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Besides the group's active_keys LIST, every active key is kept in the LIST of its tier and in the LIST
+// of its organization pool in that tier. Selection only walks the tier lists when the group has keys in
+// more than one tier or routes a premium model, so other requests keep the O(1) round robin over active_keys.
+
+// tierActiveKeysKey is the LIST of active keys in one tier of a group.
+func tierActiveKeysKey(groupID uint, tier int) string {
+	return fmt.Sprintf("group:%d:tier:%d:active_keys", groupID, tier)
+}
+
+// keyTiersKey holds the tiers used by a group's keys as a sorted, comma-separated list.
+func keyTiersKey(groupID uint) string {
+	return fmt.Sprintf("group:%d:key_tiers", groupID)
+}
+
+// keyTier reads the tier from cached key details. Keys cached before tiers existed are primary keys.
+func keyTier(keyDetails map[string]string) int {
+	tier, _ := strconv.Atoi(keyDetails["tier"])
+	return tier
+}
+
+// groupTiers returns the tiers used by the group's keys, lowest first.
+func (p *KeyProvider) groupTiers(groupID uint) ([]int, error) {
+	value, err := p.store.Get(keyTiersKey(groupID))
+	if errors.Is(err, store.ErrNotFound) {
+		return []int{0}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key tiers from store: %w", err)
+	}
+
+	var tiers []int
+	for _, part := range strings.Split(string(value), ",") {
+		tier, err := strconv.Atoi(part)
+		if err != nil {
+			continue
+		}
+		tiers = append(tiers, tier)
+	}
+	if len(tiers) == 0 {
+		return []int{0}, nil
+	}
+	return tiers, nil
+}
+
+// setGroupTiers replaces the tiers recorded for a group.
+func (p *KeyProvider) setGroupTiers(groupID uint, tiers []int) error {
+	slices.Sort(tiers)
+	tiers = slices.Compact(tiers)
+	parts := make([]string, len(tiers))
+	for i, tier := range tiers {
+		parts[i] = strconv.Itoa(tier)
+	}
+	return p.store.Set(keyTiersKey(groupID), []byte(strings.Join(parts, ",")), 0)
+}
+
+// addGroupTier records that the group has keys in the tier. Tiers are only dropped when the keys are
+// reloaded from the database; an emptied tier just yields no candidates.
+func (p *KeyProvider) addGroupTier(groupID uint, tier int) error {
+	tiers, err := p.groupTiers(groupID)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(tiers, tier) {
+		return nil
+	}
+	return p.setGroupTiers(groupID, append(tiers, tier))
+}
+
+// activateKey puts the key at the head of the group's active list, of its tier list and of its
+// organization pool list in that tier.
+func (p *KeyProvider) activateKey(groupID, keyID uint, tier int, isOrg bool) error {
+	if err := p.store.LRem(poolActiveKeysKey(groupID, tier, keyPool(!isOrg)), 0, keyID); err != nil {
+		return fmt.Errorf("failed to LRem key %d from the other organization pool: %w", keyID, err)
+	}
+	for _, listKey := range []string{
+		fmt.Sprintf("group:%d:active_keys", groupID),
+		tierActiveKeysKey(groupID, tier),
+		poolActiveKeysKey(groupID, tier, keyPool(isOrg)),
+	} {
+		if err := p.store.LRem(listKey, 0, keyID); err != nil {
+			return fmt.Errorf("failed to LRem key %d before LPush to %s: %w", keyID, listKey, err)
+		}
+		if err := p.store.LPush(listKey, keyID); err != nil {
+			return fmt.Errorf("failed to LPush key %d to %s: %w", keyID, listKey, err)
+		}
+	}
+	return nil
+}
+
+// deactivateKey removes the key from all active lists of the group.
+func (p *KeyProvider) deactivateKey(groupID, keyID uint, tier int) error {
+	for _, listKey := range []string{
+		fmt.Sprintf("group:%d:active_keys", groupID),
+		tierActiveKeysKey(groupID, tier),
+		poolActiveKeysKey(groupID, tier, poolOrg),
+		poolActiveKeysKey(groupID, tier, poolNonOrg),
+	} {
+		if err := p.store.LRem(listKey, 0, keyID); err != nil {
+			return fmt.Errorf("failed to LRem key %d from %s: %w", keyID, listKey, err)
+		}
+	}
+	return nil
+}
+
+// tierListKeys returns the tier list and the organization pool lists of a tier.
+func tierListKeys(groupID uint, tier int) []string {
+	return []string{
+		tierActiveKeysKey(groupID, tier),
+		poolActiveKeysKey(groupID, tier, poolOrg),
+		poolActiveKeysKey(groupID, tier, poolNonOrg),
+	}
+}
+
+// UpdateKeyTier moves the key to another priority tier.
+func (p *KeyProvider) UpdateKeyTier(apiKey *models.APIKey, tier int) error {
+	if err := p.db.Model(apiKey).Update("tier", tier).Error; err != nil {
+		return fmt.Errorf("failed to update tier for key %d: %w", apiKey.ID, err)
+	}
+
+	keyHashKey := fmt.Sprintf("key:%d", apiKey.ID)
+	keyDetails, err := p.store.HGetAll(keyHashKey)
+	if err != nil {
+		return fmt.Errorf("failed to get key details for key %d: %w", apiKey.ID, err)
+	}
+	oldTier := keyTier(keyDetails)
+
+	if err := p.store.HSet(keyHashKey, map[string]any{"tier": tier}); err != nil {
+		return fmt.Errorf("failed to update tier for key %d in store: %w", apiKey.ID, err)
+	}
+	if err := p.addGroupTier(apiKey.GroupID, tier); err != nil {
+		return fmt.Errorf("failed to record tier %d for group %d: %w", tier, apiKey.GroupID, err)
+	}
+
+	if keyDetails["status"] == models.KeyStatusActive {
+		if err := p.deactivateKey(apiKey.GroupID, apiKey.ID, oldTier); err != nil {
+			return err
+		}
+		if err := p.activateKey(apiKey.GroupID, apiKey.ID, tier, isOrganizationKey(keyDetails)); err != nil {
+			return err
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "from": oldTier, "to": tier}).Info("Moved key to another tier")
+	return nil
+}
@@ -272,9 +272,48 @@ func extractAuthKey(c *gin.Context) string {
 		return key
 	}
 
+	// WebSocket subprotocol, used by browser Realtime clients that cannot set headers
+	if key := extractSubprotocolKey(c); key != "" {
+		return key
+	}
+
 	return ""
 }
 
+// extractSubprotocolKey takes the key from an "openai-insecure-api-key.<key>" WebSocket subprotocol
+// and removes it from the offered subprotocols so it is not forwarded upstream.
+func extractSubprotocolKey(c *gin.Context) string {
+	const keyPrefix = "openai-insecure-api-key."
+
+	header := c.GetHeader("Sec-WebSocket-Protocol")
+	if header == "" {
+		return ""
+	}
+
+	var key string
+	var protocols []string
+	for _, protocol := range strings.Split(header, ",") {
+		protocol = strings.TrimSpace(protocol)
+		if strings.HasPrefix(protocol, keyPrefix) && key == "" {
+			key = strings.TrimPrefix(protocol, keyPrefix)
+			continue
+		}
+		if protocol != "" {
+			protocols = append(protocols, protocol)
+		}
+	}
+	if key == "" {
+		return ""
+	}
+
+	if len(protocols) > 0 {
+		c.Request.Header.Set("Sec-WebSocket-Protocol", strings.Join(protocols, ", "))
+	} else {
+		c.Request.Header.Del("Sec-WebSocket-Protocol")
+	}
+	return key
+}
+
 // StaticCache creates a middleware for caching static resources
 func StaticCache() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -31,13 +31,36 @@ type GroupConfig struct {
 	MaxIdleConns                 *int    `json:"max_idle_conns,omitempty"`
 	MaxIdleConnsPerHost          *int    `json:"max_idle_conns_per_host,omitempty"`
 	ResponseHeaderTimeout        *int    `json:"response_header_timeout,omitempty"`
+	StreamFirstChunkTimeout      *int    `json:"stream_first_chunk_timeout,omitempty"`
+	HedgeDelayMs                 *int    `json:"hedge_delay_ms,omitempty"`
+	HedgeBudgetPercent           *int    `json:"hedge_budget_percent,omitempty"`
+	CircuitBreakerThreshold      *int    `json:"circuit_breaker_threshold,omitempty"`
+	CircuitBreakerCooldown       *int    `json:"circuit_breaker_cooldown,omitempty"`
+	UpstreamHealthCheckInterval  *int    `json:"upstream_health_check_interval,omitempty"`
+	UpstreamSelectionStrategy    *string `json:"upstream_selection_strategy,omitempty"`
+	UpstreamExplorationPercent   *int    `json:"upstream_exploration_percent,omitempty"`
 	ProxyURL                     *string `json:"proxy_url,omitempty"`
 	MaxRetries                   *int    `json:"max_retries,omitempty"`
+	RetryStatusCodes             *string `json:"retry_status_codes,omitempty"`
+	NeverRetryStatusCodes        *string `json:"never_retry_status_codes,omitempty"`
+	RetryErrorClasses            *string `json:"retry_error_classes,omitempty"`
+	RetryBackoffBaseMs           *int    `json:"retry_backoff_base_ms,omitempty"`
+	RetryBackoffMaxMs            *int    `json:"retry_backoff_max_ms,omitempty"`
+	RetryTotalTimeout            *int    `json:"retry_total_timeout,omitempty"`
+	AggregateMaxAttempts         *int    `json:"aggregate_max_attempts,omitempty"`
+	AggregateSelectionMode       *string `json:"aggregate_selection_mode,omitempty"`
 	BlacklistThreshold           *int    `json:"blacklist_threshold,omitempty"`
+	ModelCooldownSeconds         *int    `json:"model_cooldown_seconds,omitempty"`
+	KeySelectionStrategy         *string `json:"key_selection_strategy,omitempty"`
+	MaxConcurrencyPerKey         *int    `json:"max_concurrency_per_key,omitempty"`
+	KeyConcurrencyWaitMs         *int    `json:"key_concurrency_wait_ms,omitempty"`
+	PremiumOrgKeysFirst          *bool   `json:"premium_org_keys_first,omitempty"`
+	PremiumExplorationPercent    *int    `json:"premium_exploration_percent,omitempty"`
 	KeyValidationIntervalMinutes *int    `json:"key_validation_interval_minutes,omitempty"`
 	KeyValidationConcurrency     *int    `json:"key_validation_concurrency,omitempty"`
 	KeyValidationTimeoutSeconds  *int    `json:"key_validation_timeout_seconds,omitempty"`
 	EnableRequestBodyLogging     *bool   `json:"enable_request_body_logging,omitempty"`
+	EnableProtocolTranslation    *bool   `json:"enable_protocol_translation,omitempty"`
 }
 
 // HeaderRule defines a single rule for header manipulation.
@@ -47,12 +70,46 @@ type HeaderRule struct {
 	Action string `json:"action"` // "set" or "remove"
 }
 
+// CustomChannelConfig describes how the "custom" channel talks to an upstream.
+type CustomChannelConfig struct {
+	Auth       CustomAuthConfig       `json:"auth"`
+	ModelPath  string                 `json:"model_path,omitempty"` // Dot-separated JSON path of the model, e.g. "model"
+	Stream     CustomStreamConfig     `json:"stream"`
+	Validation CustomValidationConfig `json:"validation"`
+}
+
+// CustomAuthConfig declares where the key is placed on upstream requests.
+type CustomAuthConfig struct {
+	Type     string `json:"type"`               // "header", "query" or "basic"
+	Header   string `json:"header,omitempty"`   // Header name for "header", defaults to Authorization
+	Prefix   string `json:"prefix,omitempty"`   // Value prefix for "header", e.g. "Bearer "
+	Query    string `json:"query,omitempty"`    // Query parameter name for "query"
+	Username string `json:"username,omitempty"` // Fixed username for "basic"; otherwise the key is "username:password"
+}
+
+// CustomStreamConfig declares how streaming requests are detected.
+type CustomStreamConfig struct {
+	BodyPath   string `json:"body_path,omitempty"`   // JSON path of a boolean flag, defaults to "stream"
+	Query      string `json:"query,omitempty"`       // Query condition, e.g. "stream=true" or "alt=sse"
+	PathSuffix string `json:"path_suffix,omitempty"` // Path suffix, e.g. ":streamGenerateContent"
+}
+
+// CustomValidationConfig is the template of the request used to validate keys.
+type CustomValidationConfig struct {
+	Method  string            `json:"method,omitempty"`  // Defaults to POST
+	Path    string            `json:"path,omitempty"`    // Used when the group has no validation endpoint
+	Body    string            `json:"body,omitempty"`    // {{model}} is replaced with the test model
+	Headers map[string]string `json:"headers,omitempty"` // Extra headers, e.g. a vendor version header
+}
+
 // GroupSubGroup 聚合分组和子分组的关联表
 type GroupSubGroup struct {
 	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
 	GroupID    uint      `gorm:"not null;uniqueIndex:idx_group_sub" json:"group_id"`
 	SubGroupID uint      `gorm:"not null;uniqueIndex:idx_group_sub" json:"sub_group_id"`
 	Weight     int       `gorm:"default:0" json:"weight"`
+	Models     string    `gorm:"type:text" json:"models"`            // Comma-separated model names or '*' patterns it serves; empty serves all
+	Priority   int       `gorm:"not null;default:0" json:"priority"` // In priority mode, lower values are used first
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 
@@ -62,11 +119,13 @@ type GroupSubGroup struct {
 
 // SubGroupInfo 用于API响应的子分组信息
 type SubGroupInfo struct {
-	Group       Group `json:"group"`
-	Weight      int   `json:"weight"`
-	TotalKeys   int64 `json:"total_keys"`
-	ActiveKeys  int64 `json:"active_keys"`
-	InvalidKeys int64 `json:"invalid_keys"`
+	Group       Group  `json:"group"`
+	Weight      int    `json:"weight"`
+	Models      string `json:"models"`
+	Priority    int    `json:"priority"`
+	TotalKeys   int64  `json:"total_keys"`
+	ActiveKeys  int64  `json:"active_keys"`
+	InvalidKeys int64  `json:"invalid_keys"`
 }
 
 // ParentAggregateGroupInfo 用于API响应的父聚合分组信息
@@ -97,6 +156,7 @@ type Group struct {
 	HeaderRules          datatypes.JSON       `gorm:"type:json" json:"header_rules"`
 	ModelRedirectRules   datatypes.JSONMap    `gorm:"type:json" json:"model_redirect_rules"`
 	ModelRedirectStrict  bool                 `gorm:"default:false" json:"model_redirect_strict"`
+	ChannelConfig        datatypes.JSONMap    `gorm:"type:json" json:"channel_config"`
 	APIKeys              []APIKey             `gorm:"foreignKey:GroupID" json:"api_keys"`
 	SubGroups            []GroupSubGroup      `gorm:"-" json:"sub_groups,omitempty"`
 	LastValidatedAt      *time.Time           `json:"last_validated_at"`
@@ -119,23 +179,41 @@ type APIKey struct {
 	Notes                string     `gorm:"type:varchar(255);default:''" json:"notes"`
 	RequestCount         int64      `gorm:"not null;default:0" json:"request_count"`
 	FailureCount         int64      `gorm:"not null;default:0" json:"failure_count"`
+	Weight               int        `gorm:"not null;default:1" json:"weight"`     // Relative share under the weighted selection strategy
+	Tier                 int        `gorm:"not null;default:0;index" json:"tier"` // Priority tier; higher tiers are backups used only when lower tiers have no usable key
 	IsOrganizationKey    bool       `gorm:"not null;default:false" json:"is_organization_key"`
 	OrganizationID       string     `gorm:"type:varchar(255);default:''" json:"organization_id"`
 	OrganizationName     string     `gorm:"type:varchar(255);default:''" json:"organization_name"`
 	LastUsedAt           *time.Time `json:"last_used_at"`
 	CreatedAt            time.Time  `json:"created_at"`
 	UpdatedAt            time.Time  `json:"updated_at"`
+
+	// Rate-limit cooldowns, read from the store for the key list
+	CooldownUntil  *time.Time           `gorm:"-" json:"cooldown_until,omitempty"`
+	ModelCooldowns map[string]time.Time `gorm:"-" json:"model_cooldowns,omitempty"`
+}
+
+// KeyModelCapability 对应 key_model_capabilities 表，记录密钥是否可以访问某个模型
+type KeyModelCapability struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	KeyID     uint      `gorm:"not null;uniqueIndex:idx_key_model" json:"key_id"`
+	GroupID   uint      `gorm:"not null;index" json:"group_id"`
+	Model     string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_key_model" json:"model"`
+	Supported bool      `gorm:"not null" json:"supported"` // Learned from a success (true) or a "no access" error (false)
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // RequestType 请求类型常量
 const (
 	RequestTypeRetry = "retry"
 	RequestTypeFinal = "final"
+	RequestTypeHedge = "hedge" // The losing attempt of a hedged request
 )
 
 // RequestLog 对应 request_logs 表
 type RequestLog struct {
 	ID               string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	RequestID        string    `gorm:"type:varchar(36);index" json:"request_id"` // Shared by all attempts of one client request
 	Timestamp        time.Time `gorm:"not null;index" json:"timestamp"`
 	GroupID          uint      `gorm:"not null;index" json:"group_id"`
 	GroupName        string    `gorm:"type:varchar(255);index" json:"group_name"`
@@ -0,0 +1,179 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxHedgeTokens bounds the hedges a group can save up while its requests answer quickly,
+// so a burst of slow requests cannot all be hedged at once.
+const maxHedgeTokens = 10
+
+var errHedgeLost = errors.New("hedged request cancelled: the other attempt answered first")
+
+// hedgeBudget caps hedging to a share of a group's requests. Every request that may be hedged earns
+// hedge_budget_percent/100 of a token and every hedge spends a whole one.
+type hedgeBudget struct {
+	mu     sync.Mutex
+	tokens float64
+}
+
+// deposit credits the budget for a request that may be hedged.
+func (b *hedgeBudget) deposit(percent int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+float64(percent)/100, maxHedgeTokens)
+}
+
+// withdraw spends a token for a hedge, reporting false when the budget is exhausted.
+func (b *hedgeBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refund returns the token of a hedge that could not be sent.
+func (b *hedgeBudget) refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+1, maxHedgeTokens)
+}
+
+// hedgeBudgetFor returns the hedge budget of a group.
+func (ps *ProxyServer) hedgeBudgetFor(groupID uint) *hedgeBudget {
+	budget, _ := ps.hedgeBudgets.LoadOrStore(groupID, &hedgeBudget{})
+	return budget.(*hedgeBudget)
+}
+
+// hedgeAnswered reports whether a call got a response the client can be given.
+func hedgeAnswered(call *upstreamCall, policy *retryPolicy) bool {
+	return call.err == nil && (call.resp.StatusCode < http.StatusBadRequest || policy.isNeverRetry(call.resp.StatusCode))
+}
+
+// sendHedged sends the primary call and, when it has no response headers after the hedge delay, the same
+// request with a second key. The first call to answer wins and the other one is cancelled and logged as a
+// hedge. A call that fails before the other answers is recorded against its key, and the other call is
+// awaited; when both fail, the later failure goes to the retry policy. It returns the call to continue
+// with and the hedge call, if one was sent, whose key and context the caller releases.
+func (ps *ProxyServer) sendHedged(
+	c *gin.Context,
+	channelHandler channel.ChannelProxy,
+	originalGroup *models.Group,
+	group *models.Group,
+	bodyBytes []byte,
+	model string,
+	primary *upstreamCall,
+	client *http.Client,
+	delay time.Duration,
+	startTime time.Time,
+	attempt int,
+	policy *retryPolicy,
+) (*upstreamCall, *upstreamCall) {
+	budget := ps.hedgeBudgetFor(group.ID)
+	budget.deposit(group.EffectiveConfig.HedgeBudgetPercent)
+
+	results := make(chan *upstreamCall, 2)
+	send := func(call *upstreamCall) {
+		sendUpstreamCall(client, channelHandler, call)
+		results <- call
+	}
+	go send(primary)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case call := <-results:
+		return call, nil
+	case <-timer.C:
+	}
+
+	hedge := ps.prepareHedge(c, channelHandler, originalGroup, group, bodyBytes, model, budget, policy)
+	if hedge == nil {
+		return <-results, nil
+	}
+	logrus.Debugf("No response after %v for group %s, hedging request with key %s", delay, group.Name, utils.MaskAPIKey(hedge.apiKey.KeyValue))
+	go send(hedge)
+
+	first := <-results
+	second := hedge
+	if first == hedge {
+		second = primary
+	}
+
+	if hedgeAnswered(first, policy) {
+		second.cancel()
+		<-results
+		statusCode := 499
+		if second.resp != nil {
+			statusCode = second.resp.StatusCode
+			second.resp.Body.Close()
+		}
+		ps.logRequest(c, originalGroup, group, second.apiKey, startTime, statusCode, errHedgeLost, false, second.upstreamURL, channelHandler, bodyBytes, models.RequestTypeHedge, nil)
+		return first, hedge
+	}
+
+	// The first call failed; it is only the outcome of the attempt if the other one fails too.
+	if first.err != nil && app_errors.IsIgnorableError(first.err) {
+		return <-results, hedge
+	}
+	failure := ps.recordUpstreamFailure(first, group, model, attempt)
+	if first.resp != nil {
+		first.resp.Body.Close()
+	}
+	ps.logRequest(c, originalGroup, group, first.apiKey, startTime, failure.statusCode, errors.New(failure.parsedError), false, first.upstreamURL, channelHandler, bodyBytes, models.RequestTypeHedge, nil)
+	return <-results, hedge
+}
+
+// prepareHedge leases a key for the hedge and builds its request, or returns nil when the group's hedge
+// budget is spent or no key is available. The key is usually another one, and the channel picks the
+// next upstream, so the hedge does not wait in the same queue as the first attempt.
+func (ps *ProxyServer) prepareHedge(
+	c *gin.Context,
+	channelHandler channel.ChannelProxy,
+	originalGroup *models.Group,
+	group *models.Group,
+	bodyBytes []byte,
+	model string,
+	budget *hedgeBudget,
+	policy *retryPolicy,
+) *upstreamCall {
+	if !budget.withdraw() {
+		logrus.Debugf("Hedge budget of group %s is spent, waiting for the first attempt", group.Name)
+		return nil
+	}
+
+	apiKey, err := ps.keyProvider.SelectKey(group, model)
+	if err != nil {
+		logrus.Debugf("No key to hedge request for group %s: %v", group.Name, err)
+		budget.refund()
+		return nil
+	}
+
+	timeout := policy.attemptTimeout(time.Duration(group.EffectiveConfig.RequestTimeout) * time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	hedge, apiErr := ps.prepareUpstreamCall(ctx, c, channelHandler, originalGroup, group, bodyBytes, false, apiKey)
+	if apiErr != nil {
+		logrus.Debugf("Failed to prepare hedge request for group %s: %v", group.Name, apiErr)
+		cancel()
+		ps.keyProvider.ReleaseKey(apiKey)
+		budget.refund()
+		return nil
+	}
+	hedge.cancel = cancel
+	return hedge
+}
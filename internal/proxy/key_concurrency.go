@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+)
+
+// saturatedKeyPollInterval is how often a request waiting for a saturated key retries the selection.
+const saturatedKeyPollInterval = 50 * time.Millisecond
+
+// selectKey selects a key for the request. When every key is at the group's concurrency limit, it keeps
+// trying for up to key_concurrency_wait_ms, so short bursts queue instead of failing with 429.
+func (ps *ProxyServer) selectKey(ctx context.Context, group *models.Group, model string) (*models.APIKey, error) {
+	apiKey, err := ps.keyProvider.SelectKey(group, model)
+	wait := time.Duration(group.EffectiveConfig.KeyConcurrencyWaitMs) * time.Millisecond
+	if !errors.Is(err, app_errors.ErrKeysSaturated) || wait <= 0 {
+		return apiKey, err
+	}
+
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(saturatedKeyPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-deadline.C:
+			return nil, err
+		case <-ticker.C:
+			apiKey, err = ps.keyProvider.SelectKey(group, model)
+			if !errors.Is(err, app_errors.ErrKeysSaturated) {
+				return apiKey, err
+			}
+		}
+	}
+}
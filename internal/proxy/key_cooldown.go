@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxKeyCooldown bounds the cooldown taken from rate-limit headers, guarding against bogus reset times.
+const maxKeyCooldown = 24 * time.Hour
+
+// cooldownOnRateLimit puts the key into cooldown when the upstream answers 429, and reports whether
+// it did, in which case the response is not counted as a key failure. Quotas are enforced per model,
+// so with a model only that model is cooled down. The cooldown lasts until the reset time given by the
+// upstream or, for a model, the group's model cooldown. Exhausted billing quotas are left to the
+// normal failure handling, as they affect every model of the key.
+func (ps *ProxyServer) cooldownOnRateLimit(apiKey *models.APIKey, group *models.Group, model string, resp *http.Response, errorBody []byte) bool {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests || bytes.Contains(errorBody, []byte("insufficient_quota")) {
+		return false
+	}
+
+	now := time.Now()
+	until, ok := rateLimitReset(resp.Header, now)
+	if !ok {
+		until, ok = retryDelayFromBody(errorBody, now)
+	}
+	if !ok {
+		cooldown := time.Duration(group.EffectiveConfig.ModelCooldownSeconds) * time.Second
+		if model == "" || cooldown <= 0 {
+			return false
+		}
+		until = now.Add(cooldown)
+	}
+	if until.Sub(now) > maxKeyCooldown {
+		until = now.Add(maxKeyCooldown)
+	}
+	ps.keyProvider.CooldownKey(apiKey, model, until)
+	return true
+}
+
+// retryDelayFromBody reads the RetryInfo detail of a Google API error, e.g. {"retryDelay": "37s"}.
+func retryDelayFromBody(errorBody []byte, now time.Time) (time.Time, bool) {
+	if !bytes.Contains(errorBody, []byte("retryDelay")) {
+		return time.Time{}, false
+	}
+	var payload struct {
+		Error struct {
+			Details []struct {
+				RetryDelay string `json:"retryDelay"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(errorBody, &payload); err != nil {
+		return time.Time{}, false
+	}
+	for _, detail := range payload.Error.Details {
+		if detail.RetryDelay == "" {
+			continue
+		}
+		if delay, err := time.ParseDuration(detail.RetryDelay); err == nil && delay > 0 {
+			return now.Add(delay), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// rateLimitReset returns when a rate limit resets, from Retry-After or the x-ratelimit-reset-* headers
+// (OpenAI) and anthropic-ratelimit-*-reset headers (Anthropic). For the latter, limits that are exhausted
+// are preferred over the others; without a remaining count the latest reset is used.
+func rateLimitReset(header http.Header, now time.Time) (time.Time, bool) {
+	if value := header.Get("Retry-After"); value != "" {
+		if until, ok := parseResetValue(value, now); ok {
+			return until, true
+		}
+	}
+
+	var latest, latestExhausted time.Time
+	for name, values := range header {
+		lower := strings.ToLower(name)
+		var remainingHeader string
+		switch {
+		case lower == "x-ratelimit-reset":
+		case strings.HasPrefix(lower, "x-ratelimit-reset-"):
+			remainingHeader = "x-ratelimit-remaining-" + strings.TrimPrefix(lower, "x-ratelimit-reset-")
+		case strings.HasPrefix(lower, "anthropic-ratelimit-") && strings.HasSuffix(lower, "-reset"):
+			remainingHeader = strings.TrimSuffix(lower, "-reset") + "-remaining"
+		default:
+			continue
+		}
+		if len(values) == 0 {
+			continue
+		}
+		until, ok := parseResetValue(values[0], now)
+		if !ok {
+			continue
+		}
+		if until.After(latest) {
+			latest = until
+		}
+		if remainingHeader != "" && header.Get(remainingHeader) == "0" && until.After(latestExhausted) {
+			latestExhausted = until
+		}
+	}
+
+	switch {
+	case !latestExhausted.IsZero():
+		return latestExhausted, true
+	case !latest.IsZero():
+		return latest, true
+	}
+	return time.Time{}, false
+}
+
+// parseResetValue parses a reset time given as seconds ("20", "1.5"), a Go-style duration ("6m0s", "20ms"),
+// a Unix timestamp, an RFC 3339 timestamp or an HTTP date. Times not in the future are ignored.
+func parseResetValue(value string, now time.Time) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	var until time.Time
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(seconds) && !math.IsInf(seconds, 0) {
+		// Values this large are Unix timestamps rather than delays.
+		if seconds > 1e9 {
+			until = time.Unix(0, int64(seconds*float64(time.Second)))
+		} else {
+			until = now.Add(time.Duration(seconds * float64(time.Second)))
+		}
+	} else if d, err := time.ParseDuration(value); err == nil {
+		until = now.Add(d)
+	} else if t, err := time.Parse(time.RFC3339, value); err == nil {
+		until = t
+	} else if t, err := http.ParseTime(value); err == nil {
+		until = t
+	} else {
+		return time.Time{}, false
+	}
+	return until, until.After(now)
+}
+
+// writeSelectKeyError responds to a request for which no key could be selected.
+// When every key is rate limited or saturated, the client is told when to come back.
+func writeSelectKeyError(c *gin.Context, err error) int {
+	var cooldownErr *keypool.CooldownError
+	if errors.As(err, &cooldownErr) {
+		retryAfter := int(math.Ceil(time.Until(cooldownErr.Until).Seconds()))
+		c.Header("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		response.Error(c, app_errors.ErrKeysCoolingDown)
+		return http.StatusTooManyRequests
+	}
+	if errors.Is(err, app_errors.ErrKeysSaturated) {
+		c.Header("Retry-After", "1")
+		response.Error(c, app_errors.ErrKeysSaturated)
+		return http.StatusTooManyRequests
+	}
+	response.Error(c, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, err.Error()))
+	return http.StatusServiceUnavailable
+}
+
+// selectKeyErrorStatus returns the status writeSelectKeyError answers a key selection error with.
+func selectKeyErrorStatus(err error) int {
+	var cooldownErr *keypool.CooldownError
+	if errors.As(err, &cooldownErr) || errors.Is(err, app_errors.ErrKeysSaturated) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusServiceUnavailable
+}
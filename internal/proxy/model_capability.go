@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+)
+
+// maxPassThroughErrorPeek bounds the bytes of a passed-through error response read to learn key capabilities.
+const maxPassThroughErrorPeek = 64 * 1024
+
+// markModelRefused records a "no access" answer against the key's capabilities for the model, and reports
+// whether the failure was one, in which case it is not counted against the key.
+func (ps *ProxyServer) markModelRefused(apiKey *models.APIKey, group *models.Group, model string, resp *http.Response, errorBody string) bool {
+	if resp == nil || model == "" || resp.StatusCode < http.StatusBadRequest || !app_errors.IsModelAccessError(errorBody) {
+		return false
+	}
+	ps.keyProvider.MarkModelUnsupported(apiKey, group, model)
+	return true
+}
+
+// markModelRefusedInResponse looks for a "no access" answer in an error response that is passed to the
+// client as it is. The bytes read are replayed, so the client still gets the whole body.
+func (ps *ProxyServer) markModelRefusedInResponse(apiKey *models.APIKey, group *models.Group, model string, resp *http.Response) {
+	if model == "" || resp.StatusCode < http.StatusBadRequest {
+		return
+	}
+	peeked, err := io.ReadAll(io.LimitReader(resp.Body, maxPassThroughErrorPeek))
+	resp.Body = &primedBody{
+		Reader: io.MultiReader(bytes.NewReader(peeked), resp.Body),
+		closer: resp.Body,
+	}
+	if err != nil {
+		return
+	}
+	ps.markModelRefused(apiKey, group, model, resp, string(handleGzipCompression(resp, peeked)))
+}
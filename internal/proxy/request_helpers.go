@@ -6,17 +6,32 @@ import (
 	"encoding/json"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
 	"io"
 	"net/http"
 
 	"github.com/sirupsen/logrus"
 )
 
-func (ps *ProxyServer) applyParamOverrides(bodyBytes []byte, group *models.Group) ([]byte, error) {
+func (ps *ProxyServer) applyParamOverrides(bodyBytes []byte, contentType string, group *models.Group) ([]byte, error) {
 	if len(group.ParamOverrides) == 0 || len(bodyBytes) == 0 {
 		return bodyBytes, nil
 	}
 
+	// Multipart uploads take overrides as form fields; file parts are passed through untouched.
+	if utils.IsMultipartForm(contentType) {
+		fields := make(map[string]string, len(group.ParamOverrides))
+		for key, value := range group.ParamOverrides {
+			fields[key] = utils.MultipartFieldValue(value)
+		}
+		rewritten, err := utils.SetMultipartFields(bodyBytes, contentType, fields)
+		if err != nil {
+			logrus.Warnf("failed to parse multipart body for param override, passing through: %v", err)
+			return bodyBytes, nil
+		}
+		return rewritten, nil
+	}
+
 	var requestData map[string]any
 	if err := json.Unmarshal(bodyBytes, &requestData); err != nil {
 		logrus.Warnf("failed to unmarshal request body for param override, passing through: %v", err)
@@ -30,6 +45,49 @@ func (ps *ProxyServer) applyParamOverrides(bodyBytes []byte, group *models.Group
 	return json.Marshal(requestData)
 }
 
+// describeMultipartBody summarizes a multipart upload as JSON for logging, listing file metadata instead of file contents.
+func describeMultipartBody(bodyBytes []byte, contentType string) string {
+	summary, err := utils.SummarizeMultipart(bodyBytes, contentType)
+	if summary == nil {
+		return ""
+	}
+	if err != nil {
+		logrus.Debugf("Incomplete multipart body summary: %v", err)
+	}
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// logMultipartRequest logs the file metadata of a multipart upload; the text fields are only logged at debug level.
+func logMultipartRequest(groupName, path string, bodyBytes []byte, contentType string) {
+	summary, err := utils.SummarizeMultipart(bodyBytes, contentType)
+	if summary == nil {
+		return
+	}
+	if err != nil {
+		logrus.Debugf("Incomplete multipart body summary: %v", err)
+	}
+
+	entry := logrus.WithFields(logrus.Fields{
+		"group": groupName,
+		"path":  path,
+	})
+	for _, file := range summary.Files {
+		entry.WithFields(logrus.Fields{
+			"field":        file.Field,
+			"filename":     file.Filename,
+			"content_type": file.ContentType,
+			"size":         file.Size,
+		}).Info("Received multipart upload")
+	}
+	if logrus.IsLevelEnabled(logrus.DebugLevel) {
+		entry.WithField("fields", summary.Fields).Debug("Received multipart request")
+	}
+}
+
 // logUpstreamError provides a centralized way to log errors from upstream interactions.
 func logUpstreamError(context string, err error) {
 	if err == nil {
@@ -0,0 +1,175 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// retryAttemptsContextKey is the gin context key holding the []RetryAttempt of a proxied request.
+const retryAttemptsContextKey = "retry_attempts"
+
+// RetryAttempt records one upstream attempt of a proxied request.
+type RetryAttempt struct {
+	Attempt    int    `json:"attempt"`
+	Group      string `json:"group,omitempty"` // Sub-group of an aggregate group the attempt was sent to
+	KeyID      uint   `json:"key_id,omitempty"`
+	StatusCode int    `json:"status_code"`
+	ErrorClass string `json:"error_class,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	BackoffMs  int64  `json:"backoff_ms,omitempty"` // Delay before the next attempt
+	Retried    bool   `json:"retried"`
+}
+
+// RetryAttempts returns the attempt history of the request handled on this context.
+func RetryAttempts(c *gin.Context) []RetryAttempt {
+	if value, ok := c.Get(retryAttemptsContextKey); ok {
+		if attempts, ok := value.([]RetryAttempt); ok {
+			return attempts
+		}
+	}
+	return nil
+}
+
+// attemptFailure describes a failed upstream attempt that may be retried with another key.
+type attemptFailure struct {
+	statusCode   int
+	errorMessage string // Returned to the client when this is the final attempt
+	parsedError  string
+	errorClass   string // Set for failures without an upstream status
+}
+
+// retryPolicy decides whether and when a failed attempt is retried, based on the group's effective config.
+type retryPolicy struct {
+	maxRetries   int
+	retryCodes   utils.StatusCodeSet
+	neverRetry   utils.StatusCodeSet
+	errorClasses map[string]bool
+	backoffBase  time.Duration
+	backoffMax   time.Duration
+	deadline     time.Time // Zero when attempts have no total time budget
+}
+
+// newRetryPolicy builds the retry policy of a request started at startTime.
+// Malformed specs are rejected when settings are saved, so parse errors only fall back to an empty set here.
+func newRetryPolicy(cfg types.SystemSettings, startTime time.Time) *retryPolicy {
+	p := &retryPolicy{
+		maxRetries:  cfg.MaxRetries,
+		backoffBase: time.Duration(cfg.RetryBackoffBaseMs) * time.Millisecond,
+		backoffMax:  time.Duration(cfg.RetryBackoffMaxMs) * time.Millisecond,
+	}
+
+	var err error
+	if p.retryCodes, err = utils.ParseStatusCodeSet(cfg.RetryStatusCodes); err != nil {
+		logrus.Warnf("Ignoring invalid retry status codes %q: %v", cfg.RetryStatusCodes, err)
+	}
+	if p.neverRetry, err = utils.ParseStatusCodeSet(cfg.NeverRetryStatusCodes); err != nil {
+		logrus.Warnf("Ignoring invalid never-retry status codes %q: %v", cfg.NeverRetryStatusCodes, err)
+	}
+	if p.errorClasses, err = utils.ParseErrorClasses(cfg.RetryErrorClasses); err != nil {
+		logrus.Warnf("Ignoring invalid retry error classes %q: %v", cfg.RetryErrorClasses, err)
+	}
+	if cfg.RetryTotalTimeout > 0 {
+		p.deadline = startTime.Add(time.Duration(cfg.RetryTotalTimeout) * time.Second)
+	}
+	return p
+}
+
+// isNeverRetry reports whether an upstream status fails the same way on any key.
+// Such responses are passed to the client as they are and not counted against the key.
+func (p *retryPolicy) isNeverRetry(statusCode int) bool {
+	return p.neverRetry.Contains(statusCode)
+}
+
+// isRetryable reports whether the failure may succeed with another key.
+func (p *retryPolicy) isRetryable(failure *attemptFailure) bool {
+	if failure.errorClass != "" {
+		return p.errorClasses[failure.errorClass]
+	}
+	return p.retryCodes.Contains(failure.statusCode)
+}
+
+// nextDelay returns the backoff before the attempt following attempt (1-based), or false when
+// the retries are exhausted or the next attempt would start after the deadline.
+func (p *retryPolicy) nextDelay(attempt int) (time.Duration, bool) {
+	if attempt > p.maxRetries {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if p.backoffBase > 0 {
+		delay = p.backoffBase
+		for i := 1; i < attempt && delay < time.Hour; i++ {
+			delay *= 2
+		}
+		if p.backoffMax > 0 && delay > p.backoffMax {
+			delay = p.backoffMax
+		}
+		// Equal jitter: keep half of the delay and randomize the other half.
+		if half := delay / 2; half > 0 {
+			delay = half + time.Duration(rand.Int63n(int64(half)+1))
+		}
+	}
+
+	if !p.deadline.IsZero() && time.Now().Add(delay).After(p.deadline) {
+		return 0, false
+	}
+	return delay, true
+}
+
+// attemptTimeout caps a per-attempt timeout to the time left before the deadline.
+func (p *retryPolicy) attemptTimeout(timeout time.Duration) time.Duration {
+	if p.deadline.IsZero() {
+		return timeout
+	}
+	if remaining := time.Until(p.deadline); remaining < timeout {
+		return max(remaining, time.Millisecond)
+	}
+	return timeout
+}
+
+// wait sleeps for the backoff delay unless the client goes away first.
+func (p *retryPolicy) wait(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// classifyTransportError returns the error class of a failure to get a response from the upstream.
+func classifyTransportError(err error) string {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return utils.ErrorClassTimeout
+	}
+	return utils.ErrorClassConnection
+}
+
+// writeUpstreamError returns the final upstream error to the client, keeping the upstream JSON body when there is one.
+func writeUpstreamError(c *gin.Context, statusCode int, errorMessage string) {
+	var errorJSON map[string]any
+	if err := json.Unmarshal([]byte(errorMessage), &errorJSON); err == nil {
+		c.JSON(statusCode, errorJSON)
+	} else {
+		response.Error(c, app_errors.NewAPIErrorWithUpstream(statusCode, "UPSTREAM_ERROR", errorMessage))
+	}
+}
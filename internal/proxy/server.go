@@ -4,11 +4,11 @@ package proxy
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"gpt-load/internal/channel"
@@ -22,9 +22,13 @@ import (
 	"gpt-load/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
+// requestIDContextKey is the gin context key holding the ID shared by the request logs of a proxied request.
+const requestIDContextKey = "request_id"
+
 // ProxyServer represents the proxy server
 type ProxyServer struct {
 	keyProvider       *keypool.KeyProvider
@@ -34,6 +38,7 @@ type ProxyServer struct {
 	channelFactory    *channel.Factory
 	requestLogService *services.RequestLogService
 	encryptionSvc     encryption.Service
+	hedgeBudgets      sync.Map // group ID -> *hedgeBudget
 }
 
 // NewProxyServer creates a new proxy server
@@ -61,6 +66,7 @@ func NewProxyServer(
 func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 	startTime := time.Now()
 	groupName := c.Param("group_name")
+	c.Set(requestIDContextKey, uuid.NewString())
 
 	originalGroup, err := ps.groupManager.GetGroupByName(groupName)
 	if err != nil {
@@ -68,8 +74,23 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		return
 	}
 
+	// The body is read first, as aggregate groups route on the model it asks for.
+	isWebSocket := isWebSocketUpgrade(c.Request)
+	var bodyBytes []byte
+	if !isWebSocket {
+		bodyBytes, err = io.ReadAll(c.Request.Body)
+		if err != nil {
+			logrus.Errorf("Failed to read request body: %v", err)
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Failed to read request body"))
+			return
+		}
+		c.Request.Body.Close()
+	}
+
 	// Select sub-group if this is an aggregate group
-	subGroupName, err := ps.subGroupManager.SelectSubGroup(originalGroup)
+	model := ps.aggregateRequestModel(c, originalGroup, bodyBytes)
+	failover := newSubGroupFailover(originalGroup, model)
+	subGroupName, err := ps.subGroupManager.SelectSubGroup(originalGroup, model, nil)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"aggregate_group": originalGroup.Name,
@@ -87,6 +108,7 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 			return
 		}
 	}
+	failover.enter(group)
 
 	channelHandler, err := ps.channelFactory.GetChannel(group)
 	if err != nil {
@@ -94,26 +116,43 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		return
 	}
 
-	bodyBytes, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		logrus.Errorf("Failed to read request body: %v", err)
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Failed to read request body"))
+	// WebSocket sessions stay on the first sub-group: once upgraded, they cannot move.
+	if isWebSocket {
+		ps.handleWebSocketProxy(c, channelHandler, originalGroup, group, startTime)
 		return
 	}
-	c.Request.Body.Close()
 
-	finalBodyBytes, err := ps.applyParamOverrides(bodyBytes, group)
-	if err != nil {
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to apply parameter overrides: %v", err)))
-		return
+	contentType := c.GetHeader("Content-Type")
+	if utils.IsMultipartForm(contentType) {
+		logMultipartRequest(group.Name, c.Request.URL.Path, bodyBytes, contentType)
 	}
 
-	isStream := channelHandler.IsStreamRequest(c, bodyBytes)
+	for {
+		finalBodyBytes, err := ps.applyParamOverrides(bodyBytes, contentType, group)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to apply parameter overrides: %v", err)))
+			return
+		}
+
+		isStream := channelHandler.IsStreamRequest(c, bodyBytes)
+
+		group = ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, finalBodyBytes, isStream, startTime, failover)
+		if group == nil {
+			return
+		}
 
-	ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, finalBodyBytes, isStream, startTime, 0)
+		channelHandler, err = ps.channelFactory.GetChannel(group)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to get channel for group '%s': %v", group.Name, err)))
+			return
+		}
+	}
 }
 
-// executeRequestWithRetry is the core recursive function for handling requests and retries.
+// executeRequestWithRetry runs the request against the group's keys until an attempt succeeds or the
+// group's retry policy gives up. Every attempt is recorded in the history returned by RetryAttempts.
+// With sub-group failover, a group that has no key or runs out of retries hands the request over instead
+// of answering the client: the sub-group to continue with is returned, and nil once the client was answered.
 func (ps *ProxyServer) executeRequestWithRetry(
 	c *gin.Context,
 	channelHandler channel.ChannelProxy,
@@ -122,162 +161,254 @@ func (ps *ProxyServer) executeRequestWithRetry(
 	bodyBytes []byte,
 	isStream bool,
 	startTime time.Time,
-	retryCount int,
-) {
+	failover *subGroupFailover,
+) *models.Group {
 	cfg := group.EffectiveConfig
+	policy := newRetryPolicy(cfg, startTime)
+
+	// Attempts made in sub-groups the request failed over from come first.
+	history := RetryAttempts(c)
+	defer func() {
+		c.Set(retryAttemptsContextKey, history)
+		if len(history) > 1 {
+			logrus.WithFields(logrus.Fields{
+				"group":    group.Name,
+				"attempts": history,
+			}).Debug("Request finished after retries")
+		}
+	}()
 
-	apiKey, err := ps.keyProvider.SelectKey(group.ID)
-	if err != nil {
-		logrus.Errorf("Failed to select a key for group %s on attempt %d: %v", group.Name, retryCount+1, err)
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, err.Error()))
-		ps.logRequest(c, originalGroup, group, nil, startTime, http.StatusServiceUnavailable, err, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal, nil)
-		return
+	for attempt := 1; ; attempt++ {
+		attemptStart := time.Now()
+		result := ps.executeAttempt(c, channelHandler, originalGroup, group, bodyBytes, isStream, startTime, attempt, policy)
+
+		record := RetryAttempt{
+			Attempt:    len(history) + 1,
+			StatusCode: result.statusCode,
+			DurationMs: time.Since(attemptStart).Milliseconds(),
+		}
+		if group.ID != originalGroup.ID {
+			record.Group = group.Name
+		}
+		if result.apiKey != nil {
+			record.KeyID = result.apiKey.ID
+		}
+
+		if selectErr := result.selectErr; selectErr != nil {
+			record.StatusCode = selectKeyErrorStatus(selectErr)
+			record.Error = selectErr.Error()
+			next := ps.nextSubGroup(originalGroup, group, failover)
+			record.Retried = next != nil
+			history = append(history, record)
+			if next != nil {
+				ps.logRequest(c, originalGroup, group, nil, startTime, record.StatusCode, selectErr, isStream, "", channelHandler, bodyBytes, models.RequestTypeRetry, nil)
+				return next
+			}
+			writeSelectKeyError(c, selectErr)
+			ps.logRequest(c, originalGroup, group, nil, startTime, record.StatusCode, selectErr, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal, nil)
+			return nil
+		}
+		failover.spend()
+
+		failure := result.failure
+		if failure == nil {
+			history = append(history, record)
+			return nil
+		}
+		record.StatusCode = failure.statusCode
+		record.ErrorClass = failure.errorClass
+		record.Error = failure.parsedError
+
+		var delay time.Duration
+		retryable := policy.isRetryable(failure)
+		retry := retryable && !failover.exhausted()
+		if retry {
+			delay, retry = policy.nextDelay(attempt)
+		}
+		var next *models.Group
+		if retryable && !retry && !failover.exhausted() {
+			next = ps.nextSubGroup(originalGroup, group, failover)
+		}
+		record.Retried = retry || next != nil
+		record.BackoffMs = delay.Milliseconds()
+		history = append(history, record)
+
+		requestType := models.RequestTypeFinal
+		if record.Retried {
+			requestType = models.RequestTypeRetry
+		}
+		ps.logRequest(c, originalGroup, group, result.apiKey, startTime, failure.statusCode, errors.New(failure.parsedError), isStream, result.upstreamURL, channelHandler, bodyBytes, requestType, nil)
+
+		if next != nil {
+			return next
+		}
+		if !retry {
+			writeUpstreamError(c, failure.statusCode, failure.errorMessage)
+			return nil
+		}
+
+		if err := policy.wait(c.Request.Context(), delay); err != nil {
+			logrus.Debugf("Client went away while waiting %v to retry request for group %s: %v", delay, group.Name, err)
+			ps.logRequest(c, originalGroup, group, result.apiKey, startTime, 499, err, isStream, result.upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, nil)
+			return nil
+		}
 	}
+}
 
-	upstreamURL, err := channelHandler.BuildUpstreamURL(c.Request.URL, originalGroup.Name)
+// attemptResult is the outcome of a single upstream attempt.
+type attemptResult struct {
+	apiKey      *models.APIKey
+	upstreamURL string
+	statusCode  int
+	failure     *attemptFailure // Set when the attempt failed and no response was written to the client
+	selectErr   error           // Set when no key could be selected; nothing was written to the client
+}
+
+// executeAttempt sends the request with one key. It writes the response to the client unless the
+// attempt failed in a way the retry policy has to decide on, which is reported as the result's failure.
+func (ps *ProxyServer) executeAttempt(
+	c *gin.Context,
+	channelHandler channel.ChannelProxy,
+	originalGroup *models.Group,
+	group *models.Group,
+	bodyBytes []byte,
+	isStream bool,
+	startTime time.Time,
+	attempt int,
+	policy *retryPolicy,
+) attemptResult {
+	cfg := group.EffectiveConfig
+
+	// Quotas are often per model, so keys are picked for the model the client asked for.
+	model := channelHandler.ExtractModel(c, bodyBytes)
+	apiKey, err := ps.selectKey(c.Request.Context(), group, model)
 	if err != nil {
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to build upstream URL: %v", err)))
-		return
+		logrus.Errorf("Failed to select a key for group %s on attempt %d: %v", group.Name, attempt, err)
+		return attemptResult{selectErr: err}
 	}
+	defer ps.keyProvider.ReleaseKey(apiKey)
+	result := attemptResult{apiKey: apiKey}
 
 	var ctx context.Context
 	var cancel context.CancelFunc
 	if isStream {
 		ctx, cancel = context.WithCancel(c.Request.Context())
 	} else {
-		timeout := time.Duration(cfg.RequestTimeout) * time.Second
+		timeout := policy.attemptTimeout(time.Duration(cfg.RequestTimeout) * time.Second)
 		ctx, cancel = context.WithTimeout(c.Request.Context(), timeout)
 	}
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, c.Request.Method, upstreamURL, bytes.NewReader(bodyBytes))
-	if err != nil {
-		logrus.Errorf("Failed to create upstream request: %v", err)
-		response.Error(c, app_errors.ErrInternalServer)
-		return
-	}
-	req.ContentLength = int64(len(bodyBytes))
-
-	req.Header = c.Request.Header.Clone()
-
-	// Clean up client auth key
-	req.Header.Del("Authorization")
-	req.Header.Del("X-Api-Key")
-	req.Header.Del("X-Goog-Api-Key")
-
-	// Apply model redirection
-	finalBodyBytes, err := channelHandler.ApplyModelRedirect(req, bodyBytes, group)
-	if err != nil {
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
-		ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadRequest, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, nil)
-		return
-	}
-
-	// Check premium model permissions
-	requestedModel := channelHandler.ExtractModel(c, finalBodyBytes)
-	if err := ps.checkModelPermissions(requestedModel, apiKey, group); err != nil {
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
-		ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusForbidden, err, isStream, upstreamURL, channelHandler, finalBodyBytes, models.RequestTypeFinal, nil)
-		return
-	}
-
-	// Update request body if it was modified by redirection
-	if !bytes.Equal(finalBodyBytes, bodyBytes) {
-		req.Body = io.NopCloser(bytes.NewReader(finalBodyBytes))
-		req.ContentLength = int64(len(finalBodyBytes))
-	}
-
-	channelHandler.ModifyRequest(req, apiKey, group)
-
-	// Apply custom header rules
-	if len(group.HeaderRuleList) > 0 {
-		headerCtx := utils.NewHeaderVariableContextFromGin(c, group, apiKey)
-		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	call, apiErr := ps.prepareUpstreamCall(ctx, c, channelHandler, originalGroup, group, bodyBytes, isStream, apiKey)
+	result.upstreamURL = call.upstreamURL
+	if apiErr != nil {
+		response.Error(c, apiErr)
+		ps.logRequest(c, originalGroup, group, apiKey, startTime, apiErr.HTTPStatus, apiErr, isStream, call.upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, nil)
+		result.statusCode = apiErr.HTTPStatus
+		return result
 	}
+	call.cancel = cancel
 
 	var client *http.Client
 	if isStream {
 		client = channelHandler.GetStreamClient()
-		req.Header.Set("X-Accel-Buffering", "no")
+		call.req.Header.Set("X-Accel-Buffering", "no")
 	} else {
 		client = channelHandler.GetHTTPClient()
 	}
 
-	resp, err := client.Do(req)
+	if delay := time.Duration(cfg.HedgeDelayMs) * time.Millisecond; !isStream && delay > 0 {
+		var hedge *upstreamCall
+		call, hedge = ps.sendHedged(c, channelHandler, originalGroup, group, bodyBytes, model, call, client, delay, startTime, attempt, policy)
+		if hedge != nil {
+			defer ps.keyProvider.ReleaseKey(hedge.apiKey)
+			defer hedge.cancel()
+		}
+	} else {
+		sendUpstreamCall(client, channelHandler, call)
+	}
+
+	// A hedged request continues with the call that answered first.
+	apiKey, cancel = call.apiKey, call.cancel
+	upstreamURL, translator := call.upstreamURL, call.translator
+	result.apiKey, result.upstreamURL = apiKey, upstreamURL
+
+	resp, err := call.resp, call.err
 	if resp != nil {
 		defer resp.Body.Close()
 	}
 
-	// Unified error handling for retries. Exclude 404 from being a retryable error.
-	if err != nil || (resp != nil && resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound) {
+	// Unified error handling for retries. Statuses that fail the same way on any key are passed through.
+	if err != nil || (resp != nil && resp.StatusCode >= 400 && !policy.isNeverRetry(resp.StatusCode)) {
 		if err != nil && app_errors.IsIgnorableError(err) {
 			logrus.Debugf("Client-side ignorable error for key %s, aborting retries: %v", utils.MaskAPIKey(apiKey.KeyValue), err)
 			ps.logRequest(c, originalGroup, group, apiKey, startTime, 499, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, nil)
-			return
+			result.statusCode = 499
+			return result
 		}
 
-		var statusCode int
-		var errorMessage string
-		var parsedError string
+		result.failure = ps.recordUpstreamFailure(call, group, model, attempt)
+		return result
+	}
+	result.statusCode = resp.StatusCode
 
-		if err != nil {
-			statusCode = 500
-			errorMessage = err.Error()
-			parsedError = errorMessage
-			logrus.Debugf("Request failed (attempt %d/%d) for key %s: %v", retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), err)
-		} else {
-			// HTTP-level error (status >= 400)
-			statusCode = resp.StatusCode
-			errorBody, readErr := io.ReadAll(resp.Body)
-			if readErr != nil {
-				logrus.Errorf("Failed to read error body: %v", readErr)
-				errorBody = []byte("Failed to read error body")
+	if isStream {
+		channelHandler.DecodeStreamResponse(resp)
+
+		// Hold the stream back until its first data chunk, so failures before that point can still be retried.
+		if resp.StatusCode < http.StatusBadRequest {
+			timeout := policy.attemptTimeout(time.Duration(cfg.StreamFirstChunkTimeout) * time.Second)
+			if primeErr := primeStream(resp, timeout, cancel); primeErr != nil {
+				if c.Request.Context().Err() != nil {
+					ps.logRequest(c, originalGroup, group, apiKey, startTime, 499, primeErr, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, nil)
+					result.statusCode = 499
+					return result
+				}
+
+				parsedError := primeErr.Error()
+				logrus.Debugf("Stream failed before the first chunk (attempt %d/%d) for key %s: %s", attempt, cfg.MaxRetries+1, utils.MaskAPIKey(apiKey.KeyValue), parsedError)
+				ps.recordStreamFailure(channelHandler, apiKey, group, upstreamURL, parsedError)
+
+				result.failure = &attemptFailure{
+					statusCode:   http.StatusBadGateway,
+					errorMessage: parsedError,
+					parsedError:  parsedError,
+					errorClass:   utils.ErrorClassStream,
+				}
+				return result
 			}
-
-			errorBody = handleGzipCompression(resp, errorBody)
-			errorMessage = string(errorBody)
-			parsedError = app_errors.ParseUpstreamError(errorBody)
-			logrus.Debugf("Request failed with status %d (attempt %d/%d) for key %s. Parsed Error: %s", statusCode, retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), parsedError)
 		}
+	}
 
-		// 使用解析后的错误信息更新密钥状态
-		ps.keyProvider.UpdateStatus(apiKey, group, false, parsedError)
-
-		// 判断是否为最后一次尝试
-		isLastAttempt := retryCount >= cfg.MaxRetries
-		requestType := models.RequestTypeRetry
-		if isLastAttempt {
-			requestType = models.RequestTypeFinal
-		}
-
-		ps.logRequest(c, originalGroup, group, apiKey, startTime, statusCode, errors.New(parsedError), isStream, upstreamURL, channelHandler, bodyBytes, requestType, nil)
-
-		// 如果是最后一次尝试，直接返回错误，不再递归
-		if isLastAttempt {
-			var errorJSON map[string]any
-			if err := json.Unmarshal([]byte(errorMessage), &errorJSON); err == nil {
-				c.JSON(statusCode, errorJSON)
-			} else {
-				response.Error(c, app_errors.NewAPIErrorWithUpstream(statusCode, "UPSTREAM_ERROR", errorMessage))
-			}
-			return
-		}
+	if resp.StatusCode < http.StatusBadRequest {
+		// A successful call is the only reliable proof that the key can use the model.
+		ps.keyProvider.MarkModelSupported(apiKey, model)
+		// Mark key as organization-verified if premium model request succeeded
+		ps.markOrganizationVerifiedOnSuccess(apiKey, group, model)
 
-		ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, bodyBytes, isStream, startTime, retryCount+1)
-		return
+		logrus.Debugf("Request for group %s succeeded on attempt %d with key %s", group.Name, attempt, utils.MaskAPIKey(apiKey.KeyValue))
 	}
 
-	// Mark key as organization-verified if premium model request succeeded
-	// This is the only reliable way to detect organization verification - actual successful API call
-	successModel := channelHandler.ExtractModel(c, bodyBytes)
-	ps.markOrganizationVerifiedOnSuccess(apiKey, group, successModel)
-
-	logrus.Debugf("Request for group %s succeeded on attempt %d with key %s", group.Name, retryCount+1, utils.MaskAPIKey(apiKey.KeyValue))
+	// Errors passed through without a retry can still tell that the key has no access to the model.
+	ps.markModelRefusedInResponse(apiKey, group, model, resp)
 
 	// Check if this is a model list request (needs special handling)
 	if shouldInterceptModelList(c.Request.URL.Path, c.Request.Method) {
 		ps.handleModelListResponse(c, resp, group, channelHandler)
 		ps.logRequest(c, originalGroup, group, apiKey, startTime, resp.StatusCode, nil, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, nil)
+	} else if translator != nil && resp.StatusCode < http.StatusBadRequest {
+		copyTranslatedHeaders(c, resp)
+		c.Status(resp.StatusCode)
+
+		var usage *TokenUsage
+		var streamErr error
+		if isStream {
+			usage, streamErr = ps.handleTranslatedStreamingResponse(c, resp, translator)
+			streamErr = ps.failCommittedStream(c, channelHandler, apiKey, group, upstreamURL, streamErr)
+		} else {
+			usage = ps.handleTranslatedNormalResponse(c, resp, translator)
+		}
+		ps.logRequest(c, originalGroup, group, apiKey, startTime, resp.StatusCode, streamErr, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, usage)
 	} else {
 		for key, values := range resp.Header {
 			for _, value := range values {
@@ -287,13 +418,141 @@ func (ps *ProxyServer) executeRequestWithRetry(
 		c.Status(resp.StatusCode)
 
 		var usage *TokenUsage
+		var streamErr error
 		if isStream {
-			usage = ps.handleStreamingResponseWithTokens(c, resp)
+			usage, streamErr = ps.handleStreamingResponseWithTokens(c, resp)
+			streamErr = ps.failCommittedStream(c, channelHandler, apiKey, group, upstreamURL, streamErr)
 		} else {
 			usage = ps.handleNormalResponseWithTokens(c, resp)
 		}
-		ps.logRequest(c, originalGroup, group, apiKey, startTime, resp.StatusCode, nil, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, usage)
+		ps.logRequest(c, originalGroup, group, apiKey, startTime, resp.StatusCode, streamErr, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal, usage)
+	}
+
+	return result
+}
+
+// upstreamCall is the upstream request of an attempt prepared for one key, and its outcome once sent.
+type upstreamCall struct {
+	apiKey      *models.APIKey
+	req         *http.Request
+	upstreamURL string
+	translator  channel.ProtocolTranslator
+	cancel      context.CancelFunc // Cancels the request's context
+	resp        *http.Response
+	err         error
+
+	upstreamFault bool // The call failed and it is not the key's fault, e.g. the upstream's circuit breaker accounts for it
+}
+
+// prepareUpstreamCall builds the upstream request for the key, applying model redirection, protocol
+// translation and header rules. A failure is returned as the error to send to the client.
+func (ps *ProxyServer) prepareUpstreamCall(
+	ctx context.Context,
+	c *gin.Context,
+	channelHandler channel.ChannelProxy,
+	originalGroup *models.Group,
+	group *models.Group,
+	bodyBytes []byte,
+	isStream bool,
+	apiKey *models.APIKey,
+) (*upstreamCall, *app_errors.APIError) {
+	call := &upstreamCall{apiKey: apiKey}
+
+	upstreamURL, err := channelHandler.BuildUpstreamURL(c.Request.URL, originalGroup.Name)
+	if err != nil {
+		return call, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to build upstream URL: %v", err))
+	}
+	call.upstreamURL = upstreamURL
+
+	req, err := http.NewRequestWithContext(ctx, c.Request.Method, upstreamURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		logrus.Errorf("Failed to create upstream request: %v", err)
+		return call, app_errors.ErrInternalServer
+	}
+	req.ContentLength = int64(len(bodyBytes))
+
+	req.Header = c.Request.Header.Clone()
+
+	// Clean up client auth key
+	req.Header.Del("Authorization")
+	req.Header.Del("X-Api-Key")
+	req.Header.Del("X-Goog-Api-Key")
+
+	// Apply model redirection
+	finalBodyBytes, err := channelHandler.ApplyModelRedirect(req, bodyBytes, group)
+	if err != nil {
+		return call, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error())
+	}
+
+	// Translate the request into the channel's native protocol if required
+	translator := channelHandler.GetTranslator(c, group)
+	if translator != nil {
+		translatedBody, err := translator.TranslateRequest(req, finalBodyBytes, isStream)
+		if err != nil {
+			return call, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error())
+		}
+		finalBodyBytes = translatedBody
+		call.upstreamURL = req.URL.String()
+		// The response body is rewritten, so let the transport handle decompression
+		req.Header.Del("Accept-Encoding")
+	}
+	call.translator = translator
+
+	// Update request body if it was modified by redirection or translation
+	if !bytes.Equal(finalBodyBytes, bodyBytes) {
+		req.Body = io.NopCloser(bytes.NewReader(finalBodyBytes))
+		req.ContentLength = int64(len(finalBodyBytes))
+	}
+
+	channelHandler.ModifyRequest(req, apiKey, group)
+	call.req = req
+	authorizeUpstreamCall(channelHandler, call)
+
+	// Apply custom header rules
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContextFromGin(c, group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	return call, nil
+}
+
+// recordUpstreamFailure reads the failure of an upstream call that returned an error or a status >= 400.
+func (ps *ProxyServer) recordUpstreamFailure(call *upstreamCall, group *models.Group, model string, attempt int) *attemptFailure {
+	apiKey, resp, err := call.apiKey, call.resp, call.err
+	cfg := group.EffectiveConfig
+	failure := &attemptFailure{}
+	if err != nil {
+		failure.statusCode = 500
+		failure.errorMessage = err.Error()
+		failure.parsedError = failure.errorMessage
+		failure.errorClass = classifyTransportError(err)
+		logrus.Debugf("Request failed (attempt %d/%d) for key %s: %v", attempt, cfg.MaxRetries+1, utils.MaskAPIKey(apiKey.KeyValue), err)
+	} else {
+		// HTTP-level error (status >= 400)
+		failure.statusCode = resp.StatusCode
+		errorBody, readErr := io.ReadAll(resp.Body)
+		if readErr != nil {
+			logrus.Errorf("Failed to read error body: %v", readErr)
+			errorBody = []byte("Failed to read error body")
+		}
+
+		errorBody = handleGzipCompression(resp, errorBody)
+		failure.errorMessage = string(errorBody)
+		failure.parsedError = app_errors.ParseUpstreamError(errorBody)
+		logrus.Debugf("Request failed with status %d (attempt %d/%d) for key %s. Parsed Error: %s", failure.statusCode, attempt, cfg.MaxRetries+1, utils.MaskAPIKey(apiKey.KeyValue), failure.parsedError)
+	}
+
+	// 使用解析后的错误信息更新密钥状态; a rate limit only cools the key down, a refused model
+	// only takes the model off the key's capabilities, and upstream outages are left to the upstream's circuit breaker.
+	switch {
+	case call.upstreamFault:
+	case ps.cooldownOnRateLimit(apiKey, group, model, resp, []byte(failure.errorMessage)):
+	case ps.markModelRefused(apiKey, group, model, resp, failure.errorMessage):
+	default:
+		ps.keyProvider.UpdateStatus(apiKey, group, false, failure.parsedError)
 	}
+	return failure
 }
 
 // logRequest is a helper function to create and record a request log.
@@ -319,13 +578,18 @@ func (ps *ProxyServer) logRequest(
 	var requestBodyToLog, userAgent string
 
 	if group.EffectiveConfig.EnableRequestBodyLogging {
-		requestBodyToLog = utils.TruncateString(string(bodyBytes), 65000)
+		if contentType := c.GetHeader("Content-Type"); utils.IsMultipartForm(contentType) {
+			requestBodyToLog = utils.TruncateString(describeMultipartBody(bodyBytes, contentType), 65000)
+		} else {
+			requestBodyToLog = utils.TruncateString(string(bodyBytes), 65000)
+		}
 		userAgent = c.Request.UserAgent()
 	}
 
 	duration := time.Since(startTime).Milliseconds()
 
 	logEntry := &models.RequestLog{
+		RequestID:    c.GetString(requestIDContextKey),
 		GroupID:      group.ID,
 		GroupName:    group.Name,
 		IsSuccess:    finalError == nil && statusCode < 400,
@@ -379,42 +643,6 @@ func (ps *ProxyServer) logRequest(
 	}
 }
 
-// checkModelPermissions checks if the API key has permission to use the requested model.
-// Premium models prefer organization-verified keys, but still allow retry mechanism.
-func (ps *ProxyServer) checkModelPermissions(requestedModel string, apiKey *models.APIKey, group *models.Group) error {
-	if requestedModel == "" {
-		return nil
-	}
-
-	// Get effective configuration
-	cfg := group.EffectiveConfig
-
-	// Check if the requested model is in the premium models list
-	if len(cfg.PremiumModelsMap) > 0 {
-		if _, isPremium := cfg.PremiumModelsMap[requestedModel]; isPremium {
-			// This is a premium model
-			if !apiKey.IsOrganizationKey {
-				// Log a warning but don't block the request
-				// Let the upstream API decide and rely on retry mechanism
-				logrus.WithFields(logrus.Fields{
-					"model":  requestedModel,
-					"key_id": apiKey.ID,
-				}).Warn("Using non-organization key for premium model - may fail and trigger retry")
-			} else {
-				logrus.WithFields(logrus.Fields{
-					"model":           requestedModel,
-					"key_id":          apiKey.ID,
-					"organization_id": apiKey.OrganizationID,
-				}).Debug("Premium model access with organization key")
-			}
-		}
-	}
-
-	// Always return nil to allow the request to proceed
-	// The upstream API will validate and the retry mechanism will handle failures
-	return nil
-}
-
 // markOrganizationVerifiedOnSuccess marks a key as organization-verified when a premium model request succeeds.
 // This is the most reliable way to detect organization verification - an actual successful API call to a premium model.
 func (ps *ProxyServer) markOrganizationVerifiedOnSuccess(apiKey *models.APIKey, group *models.Group, model string) {
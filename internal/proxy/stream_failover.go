@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxStreamPrimeBuffer bounds the bytes held back from the client while waiting for the first data chunk.
+const maxStreamPrimeBuffer = 1024 * 1024
+
+var (
+	errStreamEmpty             = errors.New("upstream stream ended before sending any data")
+	errStreamFirstChunkTimeout = errors.New("timed out waiting for the first chunk of the upstream stream")
+)
+
+// streamFailureError is an error reported by the upstream inside a stream.
+type streamFailureError struct {
+	message string
+}
+
+func (e *streamFailureError) Error() string {
+	return e.message
+}
+
+// primedBody replays the bytes read while priming a stream before the rest of the upstream body.
+type primedBody struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (b *primedBody) Close() error {
+	return b.closer.Close()
+}
+
+// primeStream holds back an SSE response until its first meaningful data chunk arrives, so a stream
+// that fails, reports an error or stays empty can still be retried on another key before anything
+// is committed to the client. On success the response body is replaced with one that replays the
+// buffered bytes. Non-SSE streams are left untouched.
+func primeStream(resp *http.Response, timeout time.Duration, cancel context.CancelFunc) error {
+	if !strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return nil
+	}
+
+	var timedOut atomic.Bool
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			timedOut.Store(true)
+			cancel()
+		})
+		defer timer.Stop()
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	var buffered bytes.Buffer
+	var eventType string
+	for {
+		line, err := reader.ReadBytes('\n')
+		buffered.Write(line)
+
+		trimmed := bytes.TrimSpace(line)
+		switch {
+		case bytes.HasPrefix(trimmed, []byte("event:")):
+			eventType = string(bytes.TrimSpace(bytes.TrimPrefix(trimmed, []byte("event:"))))
+		case len(trimmed) == 0:
+			eventType = ""
+		}
+
+		if payload, ok := sseDataPayload(line); ok {
+			if failure := streamErrorFromEvent(eventType, payload); failure != nil {
+				return failure
+			}
+			resp.Body = &primedBody{
+				Reader: io.MultiReader(bytes.NewReader(buffered.Bytes()), reader),
+				closer: resp.Body,
+			}
+			return nil
+		}
+
+		if err != nil {
+			if timedOut.Load() {
+				return errStreamFirstChunkTimeout
+			}
+			if err == io.EOF {
+				return errStreamEmpty
+			}
+			return err
+		}
+		if buffered.Len() > maxStreamPrimeBuffer {
+			return fmt.Errorf("upstream stream sent %d bytes without any data", buffered.Len())
+		}
+	}
+}
+
+// streamErrorFromEvent recognizes error events of the OpenAI ({"error": ...}) and Anthropic ({"type":"error"}) streams.
+func streamErrorFromEvent(eventType string, payload []byte) error {
+	if eventType != "error" && !bytes.Contains(payload, []byte(`"error"`)) {
+		return nil
+	}
+	var event struct {
+		Type  string          `json:"type"`
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		if eventType == "error" {
+			return &streamFailureError{message: string(payload)}
+		}
+		return nil
+	}
+	if eventType == "error" || event.Type == "error" || (len(event.Error) > 0 && !bytes.Equal(event.Error, []byte("null"))) {
+		return &streamFailureError{message: app_errors.ParseUpstreamError(payload)}
+	}
+	return nil
+}
+
+// writeStreamErrorEvent terminates a committed stream with an error event. The frame carries both an
+// "event: error" line and an "error" object, which the OpenAI and Anthropic SDKs each surface as an API error.
+func writeStreamErrorEvent(c *gin.Context, streamErr error) {
+	payload, err := json.Marshal(map[string]any{
+		"type": "error",
+		"error": map[string]any{
+			"type":    "upstream_error",
+			"message": fmt.Sprintf("upstream stream interrupted: %v", streamErr),
+		},
+	})
+	if err != nil {
+		return
+	}
+	frame := "event: error\ndata: " + string(payload) + "\n\n"
+	if _, err := c.Writer.Write([]byte(frame)); err != nil {
+		logUpstreamError("writing stream error event", err)
+		return
+	}
+	if flusher, ok := c.Writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	logrus.Debugf("Sent stream error event to client: %v", streamErr)
+}
+
+// failCommittedStream handles an upstream failure after the stream was committed to the client:
+// the client receives an error event, unless the upstream already sent one, and the failure is recorded
+// like one before the first chunk. It returns the error to log, or nil when the stream ended normally or the
+// client went away.
+func (ps *ProxyServer) failCommittedStream(c *gin.Context, channelHandler channel.ChannelProxy, apiKey *models.APIKey, group *models.Group, upstreamURL string, streamErr error) error {
+	if streamErr == nil || c.Request.Context().Err() != nil {
+		return nil
+	}
+	var reported *streamFailureError
+	if !errors.As(streamErr, &reported) {
+		writeStreamErrorEvent(c, streamErr)
+	}
+	ps.recordStreamFailure(channelHandler, apiKey, group, upstreamURL, streamErr.Error())
+	return streamErr
+}
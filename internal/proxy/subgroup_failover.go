@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"slices"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// subGroupFailover tracks a request to an aggregate group as it fails over across sub-groups.
+// Requests without failover carry a nil *subGroupFailover.
+type subGroupFailover struct {
+	model        string        // Every sub-group the request goes to must serve the model
+	attemptsLeft int           // Upstream attempts left, negative when only the sub-groups bound them
+	tried        map[uint]bool // Sub-groups the request was sent to
+}
+
+// newSubGroupFailover returns the failover state of a request to the group, or nil when the group is
+// not an aggregate. With aggregate_max_attempts set to 0, attempts are not counted: the request tries
+// each sub-group once, with the sub-group's own retries.
+func newSubGroupFailover(group *models.Group, model string) *subGroupFailover {
+	if group.GroupType != "aggregate" {
+		return nil
+	}
+	attemptsLeft := group.EffectiveConfig.AggregateMaxAttempts
+	if attemptsLeft <= 0 {
+		attemptsLeft = -1
+	}
+	return &subGroupFailover{
+		model:        model,
+		attemptsLeft: attemptsLeft,
+		tried:        make(map[uint]bool),
+	}
+}
+
+// enter records that the request is sent to the sub-group.
+func (f *subGroupFailover) enter(group *models.Group) {
+	if f != nil {
+		f.tried[group.ID] = true
+	}
+}
+
+// spend counts an upstream attempt against the budget.
+func (f *subGroupFailover) spend() {
+	if f != nil && f.attemptsLeft > 0 {
+		f.attemptsLeft--
+	}
+}
+
+// exhausted reports whether the attempt budget is spent. Requests without failover or without a budget
+// never run out.
+func (f *subGroupFailover) exhausted() bool {
+	return f != nil && f.attemptsLeft == 0
+}
+
+// nextSubGroup picks a sub-group the request has not tried yet to fail over to from group. It returns
+// nil when the request has no failover or no other sub-group is usable. The attempt budget is left to the
+// caller: a sub-group that had no key to select made no attempt, so it hands over even with none left.
+func (ps *ProxyServer) nextSubGroup(originalGroup, group *models.Group, failover *subGroupFailover) *models.Group {
+	if failover == nil {
+		return nil
+	}
+
+	subGroupName, err := ps.subGroupManager.SelectSubGroup(originalGroup, failover.model, failover.tried)
+	if err != nil {
+		logrus.Debugf("No sub-group of %s left to fail over to from %s: %v", originalGroup.Name, group.Name, err)
+		return nil
+	}
+	next, err := ps.groupManager.GetGroupByName(subGroupName)
+	if err != nil {
+		logrus.Warnf("Failed to load sub-group %s of %s for failover: %v", subGroupName, originalGroup.Name, err)
+		return nil
+	}
+	failover.enter(next)
+
+	fields := logrus.Fields{
+		"aggregate_group": originalGroup.Name,
+		"from":            group.Name,
+		"to":              next.Name,
+	}
+	if failover.attemptsLeft >= 0 {
+		fields["attempts_left"] = failover.attemptsLeft
+	}
+	logrus.WithFields(fields).Info("Failing over to another sub-group")
+	return next
+}
+
+// aggregateRequestModel returns the model a request to an aggregate group asks for, when any of its sub-groups
+// only serves some models. Sub-groups share the aggregate's channel type, so the first one's channel reads the
+// model the way all of them would.
+func (ps *ProxyServer) aggregateRequestModel(c *gin.Context, group *models.Group, bodyBytes []byte) string {
+	if group.GroupType != "aggregate" || !slices.ContainsFunc(group.SubGroups, func(sg models.GroupSubGroup) bool { return sg.Models != "" }) {
+		return ""
+	}
+
+	subGroup, err := ps.groupManager.GetGroupByName(group.SubGroups[0].SubGroupName)
+	if err != nil {
+		logrus.Warnf("Failed to load sub-group %s of %s to read the requested model: %v", group.SubGroups[0].SubGroupName, group.Name, err)
+		return ""
+	}
+	channelHandler, err := ps.channelFactory.GetChannel(subGroup)
+	if err != nil {
+		logrus.Warnf("Failed to get channel of sub-group %s to read the requested model: %v", subGroup.Name, err)
+		return ""
+	}
+	return channelHandler.ExtractModel(c, bodyBytes)
+}
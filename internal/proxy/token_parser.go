@@ -21,7 +21,97 @@ type TokenUsage struct {
 
 // UsageResponse represents common API response with usage
 type UsageResponse struct {
-	Usage *TokenUsage `json:"usage"`
+	Usage *usagePayload `json:"usage"`
+	// Message carries the usage of an Anthropic message_start stream event
+	Message *struct {
+		Usage *usagePayload `json:"usage"`
+	} `json:"message"`
+	// Response carries the usage of a Responses API terminal stream event, e.g. response.completed
+	Response *struct {
+		Usage *usagePayload `json:"usage"`
+	} `json:"response"`
+}
+
+// usagePayload accepts the OpenAI chat, OpenAI Responses and Anthropic usage shapes
+type usagePayload struct {
+	PromptTokens             int64 `json:"prompt_tokens"`
+	CompletionTokens         int64 `json:"completion_tokens"`
+	TotalTokens              int64 `json:"total_tokens"`
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+	// Bedrock Converse reports camelCase counts
+	InputTokensCamel  int64 `json:"inputTokens"`
+	OutputTokensCamel int64 `json:"outputTokens"`
+	TotalTokensCamel  int64 `json:"totalTokens"`
+}
+
+// toTokenUsage normalizes the payload, returning nil when it carries no counts
+func (u *usagePayload) toTokenUsage() *TokenUsage {
+	if u == nil {
+		return nil
+	}
+	usage := &TokenUsage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if usage.PromptTokens == 0 {
+		usage.PromptTokens = u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens + u.InputTokensCamel
+	}
+	if usage.CompletionTokens == 0 {
+		usage.CompletionTokens = u.OutputTokens + u.OutputTokensCamel
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = u.TotalTokensCamel
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	if usage.TotalTokens == 0 {
+		return nil
+	}
+	return usage
+}
+
+// usage returns the token usage carried by the response, if any
+func (r *UsageResponse) usage() *TokenUsage {
+	if usage := r.Usage.toTokenUsage(); usage != nil {
+		return usage
+	}
+	if r.Message != nil {
+		if usage := r.Message.Usage.toTokenUsage(); usage != nil {
+			return usage
+		}
+	}
+	if r.Response != nil {
+		return r.Response.Usage.toTokenUsage()
+	}
+	return nil
+}
+
+// mergeTokenUsage combines usage reported across several stream events.
+// Anthropic streams report input tokens at the start and output tokens at the end.
+func mergeTokenUsage(current, next *TokenUsage) *TokenUsage {
+	if current == nil {
+		return next
+	}
+	if next == nil {
+		return current
+	}
+	merged := *current
+	if next.PromptTokens > 0 {
+		merged.PromptTokens = next.PromptTokens
+	}
+	if next.CompletionTokens > 0 {
+		merged.CompletionTokens = next.CompletionTokens
+	}
+	merged.TotalTokens = merged.PromptTokens + merged.CompletionTokens
+	if next.TotalTokens > merged.TotalTokens {
+		merged.TotalTokens = next.TotalTokens
+	}
+	return &merged
 }
 
 // parseTokensFromResponse parses token usage from a non-streaming response
@@ -30,7 +120,7 @@ func parseTokensFromResponse(body []byte) *TokenUsage {
 	if err := json.Unmarshal(body, &resp); err != nil {
 		return nil
 	}
-	return resp.Usage
+	return resp.usage()
 }
 
 // parseTokensFromStreamChunk parses token usage from a stream chunk (SSE format)
@@ -39,6 +129,7 @@ func parseTokensFromStreamChunk(data []byte) *TokenUsage {
 	dataStr := string(data)
 	lines := strings.Split(dataStr, "\n")
 
+	var result *TokenUsage
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 		if !strings.HasPrefix(line, "data:") {
@@ -58,15 +149,16 @@ func parseTokensFromStreamChunk(data []byte) *TokenUsage {
 			continue
 		}
 
-		if resp.Usage != nil {
-			return resp.Usage
-		}
+		result = mergeTokenUsage(result, resp.usage())
 	}
 
-	return nil
+	return result
 }
 
-func (ps *ProxyServer) handleStreamingResponseWithTokens(c *gin.Context, resp *http.Response) *TokenUsage {
+// handleStreamingResponseWithTokens relays the stream and collects usage. The returned error reports
+// an upstream failure after the stream was committed, either a read error or an error event the upstream
+// sent, which is relayed as it is; client write errors are not returned.
+func (ps *ProxyServer) handleStreamingResponseWithTokens(c *gin.Context, resp *http.Response) (*TokenUsage, error) {
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
@@ -75,10 +167,12 @@ func (ps *ProxyServer) handleStreamingResponseWithTokens(c *gin.Context, resp *h
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
 		logrus.Error("Streaming unsupported by the writer, falling back to normal response")
-		return ps.handleNormalResponseWithTokens(c, resp)
+		return ps.handleNormalResponseWithTokens(c, resp), nil
 	}
 
 	var lastUsage *TokenUsage
+	var streamErr error
+	var eventType string
 	reader := bufio.NewReader(resp.Body)
 
 	for {
@@ -86,12 +180,23 @@ func (ps *ProxyServer) handleStreamingResponseWithTokens(c *gin.Context, resp *h
 		if len(line) > 0 {
 			// Try to parse tokens from each chunk
 			if usage := parseTokensFromStreamChunk(line); usage != nil {
-				lastUsage = usage
+				lastUsage = mergeTokenUsage(lastUsage, usage)
+			}
+
+			trimmed := bytes.TrimSpace(line)
+			switch {
+			case bytes.HasPrefix(trimmed, []byte("event:")):
+				eventType = string(bytes.TrimSpace(bytes.TrimPrefix(trimmed, []byte("event:"))))
+			case len(trimmed) == 0:
+				eventType = ""
+			}
+			if payload, ok := sseDataPayload(line); ok && streamErr == nil {
+				streamErr = streamErrorFromEvent(eventType, payload)
 			}
 
 			if _, writeErr := c.Writer.Write(line); writeErr != nil {
 				logUpstreamError("writing stream to client", writeErr)
-				return lastUsage
+				return lastUsage, nil
 			}
 			flusher.Flush()
 		}
@@ -100,11 +205,14 @@ func (ps *ProxyServer) handleStreamingResponseWithTokens(c *gin.Context, resp *h
 		}
 		if err != nil {
 			logUpstreamError("reading from upstream", err)
-			return lastUsage
+			if streamErr != nil {
+				return lastUsage, streamErr
+			}
+			return lastUsage, err
 		}
 	}
 
-	return lastUsage
+	return lastUsage, streamErr
 }
 
 func (ps *ProxyServer) handleNormalResponseWithTokens(c *gin.Context, resp *http.Response) *TokenUsage {
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+
+	"gpt-load/internal/channel"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// copyTranslatedHeaders copies upstream headers that remain valid once the body has been rewritten.
+func copyTranslatedHeaders(c *gin.Context, resp *http.Response) {
+	for key, values := range resp.Header {
+		switch http.CanonicalHeaderKey(key) {
+		case "Content-Length", "Content-Encoding", "Content-Type":
+			continue
+		}
+		for _, value := range values {
+			c.Header(key, value)
+		}
+	}
+}
+
+// handleTranslatedStreamingResponse converts upstream SSE events into the client's format line by line.
+// Like handleStreamingResponseWithTokens, it returns the upstream error that cut a committed stream short.
+func (ps *ProxyServer) handleTranslatedStreamingResponse(c *gin.Context, resp *http.Response, translator channel.ProtocolTranslator) (*TokenUsage, error) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		logrus.Error("Streaming unsupported by the writer, falling back to normal response")
+		return ps.handleTranslatedNormalResponse(c, resp, translator), nil
+	}
+
+	streamTranslator := translator.NewStreamTranslator()
+	var lastUsage *TokenUsage
+
+	write := func(frame []byte) bool {
+		if len(frame) == 0 {
+			return true
+		}
+		if usage := parseTokensFromStreamChunk(frame); usage != nil {
+			lastUsage = mergeTokenUsage(lastUsage, usage)
+		}
+		if _, err := c.Writer.Write(frame); err != nil {
+			logUpstreamError("writing stream to client", err)
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if payload, ok := sseDataPayload(line); ok {
+			frame, translateErr := streamTranslator.TranslateEvent(payload)
+			if translateErr != nil {
+				logrus.Debugf("Skipping untranslatable stream event: %v", translateErr)
+			} else if !write(frame) {
+				return lastUsage, nil
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			logUpstreamError("reading from upstream", err)
+			return lastUsage, err
+		}
+	}
+
+	write(streamTranslator.Finish())
+	return lastUsage, nil
+}
+
+// handleTranslatedNormalResponse converts a complete upstream response into the client's format.
+func (ps *ProxyServer) handleTranslatedNormalResponse(c *gin.Context, resp *http.Response, translator channel.ProtocolTranslator) *TokenUsage {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logUpstreamError("reading response body", err)
+		return nil
+	}
+
+	translated, err := translator.TranslateResponse(body)
+	if err != nil {
+		logrus.Warnf("Failed to translate upstream response, returning it unchanged: %v", err)
+		translated = body
+	}
+
+	c.Header("Content-Type", "application/json")
+	if _, err := io.Copy(c.Writer, bytes.NewReader(translated)); err != nil {
+		logUpstreamError("copying response body", err)
+	}
+
+	return parseTokensFromResponse(translated)
+}
+
+// sseDataPayload returns the payload of an SSE "data:" line, skipping the [DONE] marker.
+func sseDataPayload(line []byte) ([]byte, bool) {
+	line = bytes.TrimSpace(line)
+	if !bytes.HasPrefix(line, []byte("data:")) {
+		return nil, false
+	}
+	payload := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+	if len(payload) == 0 || bytes.Equal(payload, []byte("[DONE]")) {
+		return nil, false
+	}
+	return payload, true
+}
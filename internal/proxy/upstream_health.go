@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// upstreamFailed reports whether a request failed because of the upstream rather than the key:
+// it could not be reached, timed out or answered with a 5xx status.
+func upstreamFailed(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// authorizeUpstreamCall adds the key's credentials to the call for channels that exchange the key for them.
+// When they cannot be obtained, the call fails without being sent and without counting against the key.
+func authorizeUpstreamCall(channelHandler channel.ChannelProxy, call *upstreamCall) {
+	authorizer, ok := channelHandler.(channel.RequestAuthorizer)
+	if !ok {
+		return
+	}
+	if err := authorizer.AuthorizeRequest(call.req, call.apiKey); err != nil {
+		logrus.Warnf("Failed to authorize request for key %s: %v", utils.MaskAPIKey(call.apiKey.KeyValue), err)
+		call.err = err
+		call.upstreamFault = true
+	}
+}
+
+// sendUpstreamCall sends the call and feeds its outcome and latency to the stats and circuit breaker of its upstream.
+// Requests cancelled by the client or by hedging say nothing about the upstream. Calls that already failed, because
+// their credentials could not be obtained, are not sent; credentials the upstream refuses are dropped from the cache.
+func sendUpstreamCall(client *http.Client, channelHandler channel.ChannelProxy, call *upstreamCall) {
+	if call.err != nil {
+		return
+	}
+	start := time.Now()
+	call.resp, call.err = client.Do(call.req)
+	if call.err != nil && errors.Is(call.err, context.Canceled) {
+		return
+	}
+	if call.err == nil && call.resp.StatusCode == http.StatusUnauthorized {
+		if authorizer, ok := channelHandler.(channel.RequestAuthorizer); ok {
+			authorizer.RejectAuthorization(call.req, call.apiKey)
+		}
+	}
+	failed := upstreamFailed(call.resp, call.err)
+	call.upstreamFault = channelHandler.ReportUpstreamResult(call.upstreamURL, failed, time.Since(start)) && failed
+}
+
+// recordStreamFailure reports a stream that failed after its response headers to the circuit breaker of its
+// upstream. The failure only counts against the key when no breaker tracks the upstream.
+func (ps *ProxyServer) recordStreamFailure(channelHandler channel.ChannelProxy, apiKey *models.APIKey, group *models.Group, upstreamURL, message string) {
+	if channelHandler.ReportUpstreamResult(upstreamURL, true, 0) {
+		return
+	}
+	ps.keyProvider.UpdateStatus(apiKey, group, false, message)
+}
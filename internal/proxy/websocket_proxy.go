@@ -0,0 +1,423 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxObservedMessageSize bounds the text messages buffered to read usage; larger messages are passed through unread.
+const maxObservedMessageSize = 4 * 1024 * 1024
+
+// isWebSocketUpgrade reports whether the client asks to upgrade the connection to a WebSocket.
+func isWebSocketUpgrade(r *http.Request) bool {
+	if r.Method != http.MethodGet || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleWebSocketProxy proxies a WebSocket session such as the OpenAI Realtime API.
+// The handshake is retried with other keys like a normal request; once upgraded, frames are
+// pumped in both directions and a single request log is recorded when the session ends.
+func (ps *ProxyServer) handleWebSocketProxy(
+	c *gin.Context,
+	channelHandler channel.ChannelProxy,
+	originalGroup *models.Group,
+	group *models.Group,
+	startTime time.Time,
+) {
+	cfg := group.EffectiveConfig
+	policy := newRetryPolicy(cfg, startTime)
+	// Realtime sessions carry no body; an empty body lets the model be taken from the query.
+	emptyBody := []byte{}
+	requestedModel := channelHandler.ExtractModel(c, emptyBody)
+
+	// The key of the current attempt stays leased for the whole session.
+	var leasedKey *models.APIKey
+	defer func() { ps.keyProvider.ReleaseKey(leasedKey) }()
+
+	for attempt := 1; ; attempt++ {
+		apiKey, err := ps.selectKey(c.Request.Context(), group, requestedModel)
+		if err != nil {
+			logrus.Errorf("Failed to select a key for group %s on attempt %d: %v", group.Name, attempt, err)
+			statusCode := writeSelectKeyError(c, err)
+			ps.logRequest(c, originalGroup, group, nil, startTime, statusCode, err, true, "", channelHandler, emptyBody, models.RequestTypeFinal, nil)
+			return
+		}
+		leasedKey = apiKey
+
+		upstreamURL, err := channelHandler.BuildUpstreamURL(c.Request.URL, originalGroup.Name)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to build upstream URL: %v", err)))
+			return
+		}
+
+		req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, upstreamURL, nil)
+		if err != nil {
+			logrus.Errorf("Failed to create upstream request: %v", err)
+			response.Error(c, app_errors.ErrInternalServer)
+			return
+		}
+		req.Header = c.Request.Header.Clone()
+
+		// Clean up client auth key
+		req.Header.Del("Authorization")
+		req.Header.Del("X-Api-Key")
+		req.Header.Del("X-Goog-Api-Key")
+		// Keep frames uncompressed so usage events can be read from the stream.
+		req.Header.Del("Sec-WebSocket-Extensions")
+
+		if err := applyQueryModelRedirect(req, group); err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadRequest, err, true, upstreamURL, channelHandler, emptyBody, models.RequestTypeFinal, nil)
+			return
+		}
+
+		channelHandler.ModifyRequest(req, apiKey, group)
+		call := &upstreamCall{apiKey: apiKey, req: req, upstreamURL: upstreamURL}
+		authorizeUpstreamCall(channelHandler, call)
+
+		// Apply custom header rules
+		if len(group.HeaderRuleList) > 0 {
+			headerCtx := utils.NewHeaderVariableContextFromGin(c, group, apiKey)
+			utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+		}
+
+		sendUpstreamCall(channelHandler.GetStreamClient(), channelHandler, call)
+		resp, err := call.resp, call.err
+		if err == nil && resp.StatusCode == http.StatusSwitchingProtocols {
+			if upstream, ok := resp.Body.(io.ReadWriteCloser); ok {
+				ps.keyProvider.MarkModelSupported(apiKey, requestedModel)
+				usage, pumpErr := ps.pumpWebSocket(c, resp, upstream)
+				statusCode := http.StatusSwitchingProtocols
+				if pumpErr != nil {
+					statusCode = http.StatusInternalServerError
+				}
+				ps.logRequest(c, originalGroup, group, apiKey, startTime, statusCode, pumpErr, true, upstreamURL, channelHandler, emptyBody, models.RequestTypeFinal, usage)
+				return
+			}
+			resp.Body.Close()
+			err = errors.New("upstream connection does not support WebSocket upgrade")
+		}
+
+		if err != nil && app_errors.IsIgnorableError(err) {
+			logrus.Debugf("Client-side ignorable error for key %s, aborting retries: %v", utils.MaskAPIKey(apiKey.KeyValue), err)
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, 499, err, true, upstreamURL, channelHandler, emptyBody, models.RequestTypeFinal, nil)
+			return
+		}
+
+		failure := &attemptFailure{}
+		if err != nil {
+			failure.statusCode = http.StatusBadGateway
+			failure.errorMessage = err.Error()
+			failure.parsedError = failure.errorMessage
+			failure.errorClass = classifyTransportError(err)
+		} else {
+			failure.statusCode = resp.StatusCode
+			errorBody, readErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if readErr != nil {
+				errorBody = []byte("Failed to read error body")
+			}
+			errorBody = handleGzipCompression(resp, errorBody)
+			failure.errorMessage = string(errorBody)
+			failure.parsedError = app_errors.ParseUpstreamError(errorBody)
+		}
+		logrus.Debugf("WebSocket handshake failed with status %d (attempt %d/%d) for key %s: %s", failure.statusCode, attempt, cfg.MaxRetries+1, utils.MaskAPIKey(apiKey.KeyValue), failure.parsedError)
+
+		ps.keyProvider.ReleaseKey(apiKey)
+		leasedKey = nil
+
+		// Statuses such as 404 fail the same way on any key and say nothing about this one,
+		// unless the upstream refused the model for this key. Upstream outages are left to its circuit breaker.
+		neverRetry := failure.errorClass == "" && policy.isNeverRetry(failure.statusCode)
+		switch {
+		case ps.markModelRefused(apiKey, group, requestedModel, resp, failure.errorMessage):
+		case neverRetry:
+		case call.upstreamFault:
+		case ps.cooldownOnRateLimit(apiKey, group, requestedModel, resp, []byte(failure.errorMessage)):
+		default:
+			ps.keyProvider.UpdateStatus(apiKey, group, false, failure.parsedError)
+		}
+
+		var delay time.Duration
+		retry := !neverRetry && policy.isRetryable(failure)
+		if retry {
+			delay, retry = policy.nextDelay(attempt)
+		}
+		requestType := models.RequestTypeFinal
+		if retry {
+			requestType = models.RequestTypeRetry
+		}
+		ps.logRequest(c, originalGroup, group, apiKey, startTime, failure.statusCode, errors.New(failure.parsedError), true, upstreamURL, channelHandler, emptyBody, requestType, nil)
+
+		if !retry {
+			writeUpstreamError(c, failure.statusCode, failure.errorMessage)
+			return
+		}
+		if err := policy.wait(c.Request.Context(), delay); err != nil {
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, 499, err, true, upstreamURL, channelHandler, emptyBody, models.RequestTypeFinal, nil)
+			return
+		}
+	}
+}
+
+// applyQueryModelRedirect applies the group's redirect rules to the model query parameter used by the Realtime API.
+func applyQueryModelRedirect(req *http.Request, group *models.Group) error {
+	query := req.URL.Query()
+	model := query.Get("model")
+	if model == "" || len(group.ModelRedirectMap) == 0 {
+		return nil
+	}
+	if targetModel, found := group.ModelRedirectMap[model]; found {
+		query.Set("model", targetModel)
+		req.URL.RawQuery = query.Encode()
+		return nil
+	}
+	if group.ModelRedirectStrict {
+		return fmt.Errorf("model '%s' is not configured in redirect rules", model)
+	}
+	return nil
+}
+
+// pumpWebSocket completes the client handshake and relays raw frames until either side closes.
+// Upstream frames are observed on the way through to collect usage from response.done events.
+func (ps *ProxyServer) pumpWebSocket(c *gin.Context, resp *http.Response, upstream io.ReadWriteCloser) (*TokenUsage, error) {
+	defer upstream.Close()
+
+	clientConn, clientBuf, err := c.Writer.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hijack client connection: %w", err)
+	}
+	defer clientConn.Close()
+
+	// Server read/write timeouts would otherwise cut long-lived sessions.
+	_ = clientConn.SetDeadline(time.Time{})
+
+	if _, err := clientBuf.WriteString("HTTP/1.1 101 Switching Protocols\r\n"); err != nil {
+		return nil, err
+	}
+	if err := resp.Header.Write(clientBuf); err != nil {
+		return nil, err
+	}
+	if _, err := clientBuf.WriteString("\r\n"); err != nil {
+		return nil, err
+	}
+	if err := clientBuf.Flush(); err != nil {
+		return nil, err
+	}
+
+	observer := &wsMessageObserver{}
+	done := make(chan struct{}, 2)
+	go func() {
+		// Bytes read ahead by the server's buffer belong to the session.
+		if _, err := io.Copy(upstream, clientBuf.Reader); err != nil {
+			logUpstreamError("relaying WebSocket frames to upstream", err)
+		}
+		done <- struct{}{}
+	}()
+	go func() {
+		if _, err := io.Copy(clientConn, io.TeeReader(upstream, observer)); err != nil {
+			logUpstreamError("relaying WebSocket frames to client", err)
+		}
+		done <- struct{}{}
+	}()
+
+	pending := 2
+	select {
+	case <-done:
+		pending--
+	case <-c.Request.Context().Done():
+	}
+	// Closing both connections unblocks the other direction.
+	upstream.Close()
+	clientConn.Close()
+	for ; pending > 0; pending-- {
+		<-done
+	}
+
+	return observer.usage, nil
+}
+
+// wsMessageObserver parses a WebSocket frame stream written to it and sums the usage of
+// response.done events. It never fails, so it can be used with io.TeeReader.
+type wsMessageObserver struct {
+	buf       []byte
+	remaining uint64
+	masked    bool
+	mask      [4]byte
+	maskPos   int
+	collect   bool
+	fin       bool
+	inText    bool
+	oversized bool
+	message   []byte
+	usage     *TokenUsage
+}
+
+func (o *wsMessageObserver) Write(p []byte) (int, error) {
+	o.buf = append(o.buf, p...)
+	for {
+		if o.remaining > 0 {
+			if len(o.buf) == 0 {
+				break
+			}
+			n := uint64(len(o.buf))
+			if n > o.remaining {
+				n = o.remaining
+			}
+			if o.collect {
+				o.appendPayload(o.buf[:n])
+			}
+			o.buf = o.buf[n:]
+			o.remaining -= n
+			if o.remaining > 0 {
+				break
+			}
+			o.frameDone()
+			continue
+		}
+		if !o.readHeader() {
+			break
+		}
+		if o.remaining == 0 {
+			o.frameDone()
+		}
+	}
+	// Release consumed bytes so the buffer does not grow with the session.
+	o.buf = append([]byte(nil), o.buf...)
+	return len(p), nil
+}
+
+// readHeader consumes the next frame header, returning false when more data is needed.
+func (o *wsMessageObserver) readHeader() bool {
+	if len(o.buf) < 2 {
+		return false
+	}
+	fin := o.buf[0]&0x80 != 0
+	opcode := o.buf[0] & 0x0f
+	masked := o.buf[1]&0x80 != 0
+	length := uint64(o.buf[1] & 0x7f)
+
+	offset := 2
+	switch length {
+	case 126:
+		if len(o.buf) < offset+2 {
+			return false
+		}
+		length = uint64(binary.BigEndian.Uint16(o.buf[offset:]))
+		offset += 2
+	case 127:
+		if len(o.buf) < offset+8 {
+			return false
+		}
+		length = binary.BigEndian.Uint64(o.buf[offset:])
+		offset += 8
+	}
+	if masked {
+		if len(o.buf) < offset+4 {
+			return false
+		}
+		copy(o.mask[:], o.buf[offset:offset+4])
+		offset += 4
+	}
+	o.buf = o.buf[offset:]
+	o.remaining = length
+	o.masked = masked
+	o.maskPos = 0
+	o.fin = fin
+
+	switch opcode {
+	case 0x1: // text
+		o.inText = true
+		o.oversized = false
+		o.message = o.message[:0]
+		o.collect = true
+	case 0x0: // continuation
+		o.collect = o.inText
+	default: // binary and control frames
+		o.collect = false
+		if opcode < 0x8 {
+			o.inText = false
+		}
+	}
+	return true
+}
+
+func (o *wsMessageObserver) appendPayload(data []byte) {
+	if o.oversized {
+		return
+	}
+	if len(o.message)+len(data) > maxObservedMessageSize {
+		o.oversized = true
+		o.message = o.message[:0]
+		return
+	}
+	start := len(o.message)
+	o.message = append(o.message, data...)
+	if o.masked {
+		for i := start; i < len(o.message); i++ {
+			o.message[i] ^= o.mask[o.maskPos%4]
+			o.maskPos++
+		}
+	}
+}
+
+// frameDone finishes the current frame, handling the message once its final fragment arrived.
+func (o *wsMessageObserver) frameDone() {
+	if !o.collect || !o.fin {
+		return
+	}
+	o.inText = false
+	o.collect = false
+	if !o.oversized {
+		o.handleMessage(o.message)
+	}
+	o.message = o.message[:0]
+}
+
+// handleMessage adds the usage of a response.done event; each event reports one response.
+func (o *wsMessageObserver) handleMessage(message []byte) {
+	if !bytes.Contains(message, []byte(`"response.done"`)) {
+		return
+	}
+	var event struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(message, &event); err != nil || event.Type != "response.done" {
+		return
+	}
+	usage := parseTokensFromResponse(message)
+	if usage == nil {
+		return
+	}
+	if o.usage == nil {
+		o.usage = &TokenUsage{}
+	}
+	o.usage.PromptTokens += usage.PromptTokens
+	o.usage.CompletionTokens += usage.CompletionTokens
+	o.usage.TotalTokens += usage.TotalTokens
+}
@@ -121,6 +121,8 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		groups.GET("/:id/sub-groups", serverHandler.GetSubGroups)
 		groups.POST("/:id/sub-groups", serverHandler.AddSubGroups)
 		groups.PUT("/:id/sub-groups/:subGroupId/weight", serverHandler.UpdateSubGroupWeight)
+		groups.PUT("/:id/sub-groups/:subGroupId/models", serverHandler.UpdateSubGroupModels)
+		groups.PUT("/:id/sub-groups/:subGroupId/priority", serverHandler.UpdateSubGroupPriority)
 		groups.DELETE("/:id/sub-groups/:subGroupId", serverHandler.DeleteSubGroup)
 		groups.GET("/:id/parent-aggregate-groups", serverHandler.GetParentAggregateGroups)
 	}
@@ -130,6 +132,7 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 	{
 		keys.GET("", serverHandler.ListKeysInGroup)
 		keys.GET("/export", serverHandler.ExportKeys)
+		keys.GET("/capabilities", serverHandler.GetKeyCapabilities)
 		keys.POST("/add-multiple", serverHandler.AddMultipleKeys)
 		keys.POST("/add-async", serverHandler.AddMultipleKeysAsync)
 		keys.POST("/delete-multiple", serverHandler.DeleteMultipleKeys)
@@ -141,6 +144,8 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		keys.POST("/validate-group", serverHandler.ValidateGroupKeys)
 		keys.POST("/test-multiple", serverHandler.TestMultipleKeys)
 		keys.PUT("/:id/notes", serverHandler.UpdateKeyNotes)
+		keys.PUT("/:id/weight", serverHandler.UpdateKeyWeight)
+		keys.PUT("/:id/tier", serverHandler.UpdateKeyTier)
 	}
 
 	// Tasks
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"strings"
 	"sync"
 
 	app_errors "gpt-load/internal/errors"
@@ -14,8 +15,10 @@ import (
 
 // SubGroupInput defines the input payload for aggregate group member configuration.
 type SubGroupInput struct {
-	GroupID uint `json:"group_id"`
-	Weight  int  `json:"weight"`
+	GroupID  uint   `json:"group_id"`
+	Weight   int    `json:"weight"`
+	Models   string `json:"models"`
+	Priority int    `json:"priority"`
 }
 
 // AggregateValidationResult captures the normalized aggregate group parameters.
@@ -55,6 +58,9 @@ func (s *AggregateGroupService) ValidateSubGroups(ctx context.Context, channelTy
 		if input.Weight > 1000 {
 			return nil, NewI18nError(app_errors.ErrValidation, "validation.sub_group_weight_max_exceeded", nil)
 		}
+		if input.Priority < 0 {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.sub_group_priority_negative", nil)
+		}
 		subGroupIDs = append(subGroupIDs, input.GroupID)
 	}
 
@@ -100,6 +106,8 @@ func (s *AggregateGroupService) ValidateSubGroups(ctx context.Context, channelTy
 		resultSubGroups = append(resultSubGroups, models.GroupSubGroup{
 			SubGroupID: input.GroupID,
 			Weight:     input.Weight,
+			Models:     normalizeSubGroupModels(input.Models),
+			Priority:   input.Priority,
 		})
 	}
 
@@ -134,10 +142,14 @@ func (s *AggregateGroupService) GetSubGroups(ctx context.Context, groupID uint)
 
 	subGroupIDs := make([]uint, 0, len(groupSubGroups))
 	weightMap := make(map[uint]int, len(groupSubGroups))
+	modelsMap := make(map[uint]string, len(groupSubGroups))
+	priorityMap := make(map[uint]int, len(groupSubGroups))
 
 	for _, gsg := range groupSubGroups {
 		subGroupIDs = append(subGroupIDs, gsg.SubGroupID)
 		weightMap[gsg.SubGroupID] = gsg.Weight
+		modelsMap[gsg.SubGroupID] = gsg.Models
+		priorityMap[gsg.SubGroupID] = gsg.Priority
 	}
 
 	var subGroupModels []models.Group
@@ -160,6 +172,8 @@ func (s *AggregateGroupService) GetSubGroups(ctx context.Context, groupID uint)
 		subGroups = append(subGroups, models.SubGroupInfo{
 			Group:       subGroup,
 			Weight:      weightMap[subGroup.ID],
+			Models:      modelsMap[subGroup.ID],
+			Priority:    priorityMap[subGroup.ID],
 			TotalKeys:   stats.TotalKeys,
 			ActiveKeys:  stats.ActiveKeys,
 			InvalidKeys: stats.InvalidKeys,
@@ -292,6 +306,103 @@ func (s *AggregateGroupService) UpdateSubGroupWeight(ctx context.Context, groupI
 	return nil
 }
 
+// UpdateSubGroupModels updates the models a specific sub group serves
+func (s *AggregateGroupService) UpdateSubGroupModels(ctx context.Context, groupID, subGroupID uint, modelList string) error {
+	var group models.Group
+	if err := s.db.WithContext(ctx).First(&group, groupID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return NewI18nError(app_errors.ErrResourceNotFound, "group.not_found", nil)
+		}
+		return err
+	}
+
+	if group.GroupType != "aggregate" {
+		return NewI18nError(app_errors.ErrBadRequest, "group.not_aggregate", nil)
+	}
+
+	// 检查子分组关联是否存在
+	var existingRecord models.GroupSubGroup
+	if err := s.db.WithContext(ctx).Where("group_id = ? AND sub_group_id = ?", groupID, subGroupID).First(&existingRecord).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return NewI18nError(app_errors.ErrResourceNotFound, "group.sub_group_not_found", nil)
+		}
+		return err
+	}
+
+	result := s.db.WithContext(ctx).
+		Model(&models.GroupSubGroup{}).
+		Where("group_id = ? AND sub_group_id = ?", groupID, subGroupID).
+		Update("models", normalizeSubGroupModels(modelList))
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return NewI18nError(app_errors.ErrResourceNotFound, "group.sub_group_not_found", nil)
+	}
+
+	// 触发缓存更新
+	if err := s.groupManager.Invalidate(); err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache after updating sub group models")
+	}
+
+	return nil
+}
+
+// UpdateSubGroupPriority updates the priority of a specific sub group
+func (s *AggregateGroupService) UpdateSubGroupPriority(ctx context.Context, groupID, subGroupID uint, priority int) error {
+	var group models.Group
+	if err := s.db.WithContext(ctx).First(&group, groupID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return NewI18nError(app_errors.ErrResourceNotFound, "group.not_found", nil)
+		}
+		return err
+	}
+
+	if group.GroupType != "aggregate" {
+		return NewI18nError(app_errors.ErrBadRequest, "group.not_aggregate", nil)
+	}
+
+	if priority < 0 {
+		return NewI18nError(app_errors.ErrValidation, "validation.sub_group_priority_negative", nil)
+	}
+
+	// 检查子分组关联是否存在
+	var existingRecord models.GroupSubGroup
+	if err := s.db.WithContext(ctx).Where("group_id = ? AND sub_group_id = ?", groupID, subGroupID).First(&existingRecord).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return NewI18nError(app_errors.ErrResourceNotFound, "group.sub_group_not_found", nil)
+		}
+		return err
+	}
+
+	result := s.db.WithContext(ctx).
+		Model(&models.GroupSubGroup{}).
+		Where("group_id = ? AND sub_group_id = ?", groupID, subGroupID).
+		Update("priority", priority)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return NewI18nError(app_errors.ErrResourceNotFound, "group.sub_group_not_found", nil)
+	}
+
+	// 触发缓存更新
+	if err := s.groupManager.Invalidate(); err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache after updating sub group priority")
+	}
+
+	return nil
+}
+
+// normalizeSubGroupModels trims the entries of a comma-separated model list and drops empty ones
+func normalizeSubGroupModels(modelList string) string {
+	return strings.Join(utils.SplitAndTrim(modelList, ","), ",")
+}
+
 // DeleteSubGroup removes a sub group from an aggregate group
 func (s *AggregateGroupService) DeleteSubGroup(ctx context.Context, groupID, subGroupID uint) error {
 	var group models.Group
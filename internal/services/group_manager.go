@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"gpt-load/internal/channel"
 	"gpt-load/internal/config"
 	"gpt-load/internal/models"
 	"gpt-load/internal/store"
@@ -23,6 +24,7 @@ type GroupManager struct {
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
 	subGroupManager *SubGroupManager
+	channelFactory  *channel.Factory
 }
 
 // NewGroupManager creates a new, uninitialized GroupManager.
@@ -31,12 +33,14 @@ func NewGroupManager(
 	store store.Store,
 	settingsManager *config.SystemSettingsManager,
 	subGroupManager *SubGroupManager,
+	channelFactory *channel.Factory,
 ) *GroupManager {
 	return &GroupManager{
 		db:              db,
 		store:           store,
 		settingsManager: settingsManager,
 		subGroupManager: subGroupManager,
+		channelFactory:  channelFactory,
 	}
 }
 
@@ -133,6 +137,7 @@ func (gm *GroupManager) Initialize() error {
 
 	afterReload := func(newCache map[string]*models.Group) {
 		gm.subGroupManager.RebuildSelectors(newCache)
+		gm.channelFactory.PruneChannels(newCache)
 	}
 
 	syncer, err := syncer.NewCacheSyncer(
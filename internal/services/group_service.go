@@ -97,6 +97,7 @@ type GroupCreateParams struct {
 	ModelRedirectRules  map[string]string
 	ModelRedirectStrict bool
 	Config              map[string]any
+	ChannelConfig       map[string]any
 	HeaderRules         []models.HeaderRule
 	ProxyKeys           string
 	SubGroups           []SubGroupInput
@@ -119,6 +120,7 @@ type GroupUpdateParams struct {
 	ModelRedirectRules  map[string]string
 	ModelRedirectStrict *bool
 	Config              map[string]any
+	ChannelConfig       map[string]any
 	HeaderRules         *[]models.HeaderRule
 	ProxyKeys           *string
 	SubGroups           *[]SubGroupInput
@@ -126,9 +128,10 @@ type GroupUpdateParams struct {
 
 // KeyStats captures aggregated API key statistics for a group.
 type KeyStats struct {
-	TotalKeys   int64 `json:"total_keys"`
-	ActiveKeys  int64 `json:"active_keys"`
-	InvalidKeys int64 `json:"invalid_keys"`
+	TotalKeys   int64          `json:"total_keys"`
+	ActiveKeys  int64          `json:"active_keys"`
+	InvalidKeys int64          `json:"invalid_keys"`
+	TierCounts  []TierKeyCount `json:"tier_counts"`
 }
 
 // RequestStats captures request success and failure ratios over a time window.
@@ -152,6 +155,8 @@ type ConfigOption struct {
 	Name         string
 	Description  string
 	DefaultValue any
+	MinValue     *int
+	MaxValue     *int
 }
 
 // CreateGroup validates and persists a new group.
@@ -206,6 +211,11 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		return nil, err
 	}
 
+	channelConfig := datatypes.JSONMap(params.ChannelConfig)
+	if err := validateChannelConfig(channelType, groupType, channelConfig); err != nil {
+		return nil, err
+	}
+
 	headerRulesJSON, err := s.normalizeHeaderRules(params.HeaderRules)
 	if err != nil {
 		return nil, err
@@ -238,6 +248,7 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		ModelRedirectRules:  convertToJSONMap(params.ModelRedirectRules),
 		ModelRedirectStrict: params.ModelRedirectStrict,
 		Config:              cleanedConfig,
+		ChannelConfig:       channelConfig,
 		HeaderRules:         headerRulesJSON,
 		ProxyKeys:           strings.TrimSpace(params.ProxyKeys),
 	}
@@ -396,6 +407,15 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 		group.Config = cleanedConfig
 	}
 
+	if params.ChannelConfig != nil {
+		group.ChannelConfig = datatypes.JSONMap(params.ChannelConfig)
+	}
+	if params.ChannelConfig != nil || params.ChannelType != nil {
+		if err := validateChannelConfig(group.ChannelType, group.GroupType, group.ChannelConfig); err != nil {
+			return nil, err
+		}
+	}
+
 	if params.ProxyKeys != nil {
 		group.ProxyKeys = strings.TrimSpace(*params.ProxyKeys)
 	}
@@ -457,6 +477,10 @@ func (s *GroupService) DeleteGroup(ctx context.Context, id uint) error {
 		return app_errors.ParseDBError(err)
 	}
 
+	if err := tx.Where("group_id = ?", id).Delete(&models.KeyModelCapability{}).Error; err != nil {
+		return app_errors.ErrDatabase
+	}
+
 	if err := tx.Where("group_id = ?", id).Delete(&models.APIKey{}).Error; err != nil {
 		return app_errors.ErrDatabase
 	}
@@ -627,10 +651,16 @@ func (s *GroupService) fetchKeyStats(ctx context.Context, groupID uint) (KeyStat
 		return KeyStats{}, fmt.Errorf("failed to get active keys: %w", err)
 	}
 
+	tierCounts, err := countKeysByTier(s.db.WithContext(ctx), groupID)
+	if err != nil {
+		return KeyStats{}, fmt.Errorf("failed to get tier key counts: %w", err)
+	}
+
 	return KeyStats{
 		TotalKeys:   totalKeys,
 		ActiveKeys:  activeKeys,
 		InvalidKeys: totalKeys - activeKeys,
+		TierCounts:  tierCounts,
 	}, nil
 }
 
@@ -769,6 +799,8 @@ func (s *GroupService) GetGroupConfigOptions() ([]ConfigOption, error) {
 			Name:         definition.Name,
 			Description:  definition.Description,
 			DefaultValue: defaultValue,
+			MinValue:     definition.MinValue,
+			MaxValue:     definition.MaxValue,
 		})
 	}
 
@@ -991,6 +1023,17 @@ func convertToJSONMap(input map[string]string) datatypes.JSONMap {
 	return result
 }
 
+// validateChannelConfig checks the channel config of standard groups whose channel type is driven by it.
+func validateChannelConfig(channelType, groupType string, channelConfig datatypes.JSONMap) error {
+	if channelType != "custom" || groupType == "aggregate" {
+		return nil
+	}
+	if _, err := channel.ParseCustomChannelConfig(channelConfig); err != nil {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_channel_config", map[string]any{"error": err.Error()})
+	}
+	return nil
+}
+
 // validateModelRedirectRules validates the format and content of model redirect rules
 func validateModelRedirectRules(rules map[string]string) error {
 	if len(rules) == 0 {
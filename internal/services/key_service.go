@@ -1,13 +1,16 @@
 package services
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/keypool"
 	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
 	"io"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -131,6 +134,7 @@ func (s *KeyService) processAndCreateKeys(
 			KeyValue: encryptedKey,
 			KeyHash:  keyHash,
 			Status:   models.KeyStatusActive,
+			Weight:   1,
 		})
 	}
 
@@ -168,6 +172,11 @@ func (s *KeyService) ParseKeysFromText(text string) []string {
 		return s.filterValidKeys(keys)
 	}
 
+	// JSON credentials (e.g. service account keys) contain separators, so keep each object whole
+	if objectKeys := parseJSONObjectKeys(text); len(objectKeys) > 0 {
+		return s.filterValidKeys(objectKeys)
+	}
+
 	// 通用解析：通过分隔符分割文本，不使用复杂的正则表达式
 	delimiters := regexp.MustCompile(`[\s,;\n\r\t]+`)
 	splitKeys := delimiters.Split(strings.TrimSpace(text), -1)
@@ -182,6 +191,41 @@ func (s *KeyService) ParseKeysFromText(text string) []string {
 	return s.filterValidKeys(keys)
 }
 
+// parseJSONObjectKeys parses a JSON object, an array of objects, or a sequence of objects
+// into one compact JSON string per object.
+func parseJSONObjectKeys(text string) []string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[{") {
+		return nil
+	}
+
+	var objects []json.RawMessage
+	if strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal([]byte(trimmed), &objects); err != nil {
+			return nil
+		}
+	} else {
+		decoder := json.NewDecoder(strings.NewReader(trimmed))
+		for decoder.More() {
+			var obj json.RawMessage
+			if err := decoder.Decode(&obj); err != nil {
+				return nil
+			}
+			objects = append(objects, obj)
+		}
+	}
+
+	keys := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, obj); err != nil || !bytes.HasPrefix(buf.Bytes(), []byte("{")) {
+			return nil
+		}
+		keys = append(keys, buf.String())
+	}
+	return keys
+}
+
 // filterValidKeys validates and filters potential API keys
 func (s *KeyService) filterValidKeys(keys []string) []string {
 	var validKeys []string
@@ -316,6 +360,115 @@ func (s *KeyService) ListKeysInGroupQuery(
 	return query
 }
 
+// TierKeyCount holds the key counts of one priority tier of a group.
+type TierKeyCount struct {
+	Tier       int   `json:"tier"`
+	TotalKeys  int64 `json:"total_keys"`
+	ActiveKeys int64 `json:"active_keys"`
+}
+
+// CountKeysByTier returns the total and active key counts of each tier in the group, lowest tier first.
+func (s *KeyService) CountKeysByTier(groupID uint) ([]TierKeyCount, error) {
+	return countKeysByTier(s.DB, groupID)
+}
+
+func countKeysByTier(db *gorm.DB, groupID uint) ([]TierKeyCount, error) {
+	var counts []TierKeyCount
+	err := db.Model(&models.APIKey{}).
+		Select("tier, COUNT(*) AS total_keys, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS active_keys", models.KeyStatusActive).
+		Where("group_id = ?", groupID).
+		Group("tier").
+		Order("tier").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// ModelCapabilitySummary counts the keys of a group learned to support or refuse a model.
+type ModelCapabilitySummary struct {
+	Model           string `json:"model"`
+	SupportedKeys   int    `json:"supported_keys"`
+	UnsupportedKeys int    `json:"unsupported_keys"`
+}
+
+// KeyCapabilities holds the learned model capabilities of one key. Models the key was never used with are absent.
+type KeyCapabilities struct {
+	KeyID    uint            `json:"key_id"`
+	KeyValue string          `json:"key_value"`
+	Status   string          `json:"status"`
+	Tier     int             `json:"tier"`
+	Models   map[string]bool `json:"models"`
+}
+
+// CapabilityMatrix is the model capability matrix of a group's keys.
+type CapabilityMatrix struct {
+	Models []ModelCapabilitySummary `json:"models"`
+	Keys   []KeyCapabilities        `json:"keys"`
+}
+
+// GetCapabilityMatrix returns the model capabilities learned for the keys of a group, with masked key values.
+func (s *KeyService) GetCapabilityMatrix(groupID uint) (*CapabilityMatrix, error) {
+	var capabilities []models.KeyModelCapability
+	if err := s.DB.Where("group_id = ?", groupID).Order("key_id, model").Find(&capabilities).Error; err != nil {
+		return nil, err
+	}
+
+	matrix := &CapabilityMatrix{Models: []ModelCapabilitySummary{}, Keys: []KeyCapabilities{}}
+	if len(capabilities) == 0 {
+		return matrix, nil
+	}
+
+	keyIDs := make([]uint, 0, len(capabilities))
+	for _, capability := range capabilities {
+		if len(keyIDs) == 0 || keyIDs[len(keyIDs)-1] != capability.KeyID {
+			keyIDs = append(keyIDs, capability.KeyID)
+		}
+	}
+	var keys []models.APIKey
+	if err := s.DB.Select("id, key_value, status, tier").Where("id IN ?", keyIDs).Order("tier, id").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+
+	keyModels := make(map[uint]map[string]bool, len(keys))
+	summaries := make(map[string]*ModelCapabilitySummary)
+	for _, capability := range capabilities {
+		if keyModels[capability.KeyID] == nil {
+			keyModels[capability.KeyID] = make(map[string]bool)
+		}
+		keyModels[capability.KeyID][capability.Model] = capability.Supported
+
+		summary, ok := summaries[capability.Model]
+		if !ok {
+			summary = &ModelCapabilitySummary{Model: capability.Model}
+			summaries[capability.Model] = summary
+		}
+		if capability.Supported {
+			summary.SupportedKeys++
+		} else {
+			summary.UnsupportedKeys++
+		}
+	}
+
+	for _, summary := range summaries {
+		matrix.Models = append(matrix.Models, *summary)
+	}
+	sort.Slice(matrix.Models, func(i, j int) bool { return matrix.Models[i].Model < matrix.Models[j].Model })
+
+	for _, key := range keys {
+		keyValue, err := s.EncryptionSvc.Decrypt(key.KeyValue)
+		if err != nil {
+			keyValue = key.KeyValue
+		}
+		matrix.Keys = append(matrix.Keys, KeyCapabilities{
+			KeyID:    key.ID,
+			KeyValue: utils.MaskAPIKey(keyValue),
+			Status:   key.Status,
+			Tier:     key.Tier,
+			Models:   keyModels[key.ID],
+		})
+	}
+	return matrix, nil
+}
+
 // TestMultipleKeys handles a one-off validation test for multiple keys.
 func (s *KeyService) TestMultipleKeys(group *models.Group, keysText string) ([]keypool.KeyTestResult, error) {
 	keysToTest := s.ParseKeysFromText(keysText)
@@ -59,6 +59,9 @@ func (s *LogService) logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB
 		if requestType := c.Query("request_type"); requestType != "" {
 			db = db.Where("request_type = ?", requestType)
 		}
+		if requestID := c.Query("request_id"); requestID != "" {
+			db = db.Where("request_id = ?", requestID)
+		}
 		if statusCodeStr := c.Query("status_code"); statusCodeStr != "" {
 			if statusCode, err := strconv.Atoi(statusCodeStr); err == nil {
 				db = db.Where("status_code = ?", statusCode)
@@ -239,7 +239,7 @@ func (s *RequestLogService) writeLogsToDB(logs []*models.RequestLog) error {
 			GroupID uint
 		}]struct{ Success, Failure int64 })
 		for _, log := range logs {
-			if log.RequestType == models.RequestTypeRetry {
+			if log.RequestType == models.RequestTypeRetry || log.RequestType == models.RequestTypeHedge {
 				continue
 			}
 			hourlyTime := log.Timestamp.Truncate(time.Hour)
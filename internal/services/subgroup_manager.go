@@ -2,18 +2,25 @@ package services
 
 import (
 	"fmt"
+	"gpt-load/internal/channel"
 	"gpt-load/internal/models"
 	"gpt-load/internal/store"
+	"gpt-load/internal/utils"
+	"slices"
 	"sync"
 
 	"github.com/sirupsen/logrus"
 )
 
-// SubGroupManager manages weighted round-robin selection for all aggregate groups
+// aggregateModePriority is the aggregate_selection_mode that uses sub-groups in priority order.
+const aggregateModePriority = "priority"
+
+// SubGroupManager manages weighted round-robin and priority selection for all aggregate groups
 type SubGroupManager struct {
-	store     store.Store
-	selectors map[uint]*selector
-	mu        sync.RWMutex
+	store          store.Store
+	channelFactory *channel.Factory
+	selectors      map[uint]*selector
+	mu             sync.RWMutex
 }
 
 // subGroupItem represents a sub-group with its weight and current weight for round-robin
@@ -22,18 +29,36 @@ type subGroupItem struct {
 	subGroupID    uint
 	weight        int
 	currentWeight int
+	models        []string // Model names or patterns the sub-group serves; empty serves all
+	priority      int
+}
+
+// serves reports whether the sub-group serves the model. Requests without a model, such as model
+// listings, can go to any sub-group.
+func (item *subGroupItem) serves(model string) bool {
+	if len(item.models) == 0 || model == "" {
+		return true
+	}
+	for _, pattern := range item.models {
+		if utils.MatchWildcard(pattern, model) {
+			return true
+		}
+	}
+	return false
 }
 
 // NewSubGroupManager creates a new sub-group manager service
-func NewSubGroupManager(store store.Store) *SubGroupManager {
+func NewSubGroupManager(store store.Store, channelFactory *channel.Factory) *SubGroupManager {
 	return &SubGroupManager{
-		store:     store,
-		selectors: make(map[uint]*selector),
+		store:          store,
+		channelFactory: channelFactory,
+		selectors:      make(map[uint]*selector),
 	}
 }
 
-// SelectSubGroup selects an appropriate sub-group for the given aggregate group
-func (m *SubGroupManager) SelectSubGroup(group *models.Group) (string, error) {
+// SelectSubGroup selects an appropriate sub-group for the given aggregate group among those serving the model,
+// skipping the sub-groups in exclude, e.g. those a failed-over request already tried.
+func (m *SubGroupManager) SelectSubGroup(group *models.Group, model string, exclude map[uint]bool) (string, error) {
 	if group.GroupType != "aggregate" {
 		return "", nil
 	}
@@ -43,14 +68,15 @@ func (m *SubGroupManager) SelectSubGroup(group *models.Group) (string, error) {
 		return "", fmt.Errorf("no valid sub-groups available for aggregate group '%s'", group.Name)
 	}
 
-	selectedName := selector.selectNext()
+	selectedName := selector.selectNext(model, exclude)
 	if selectedName == "" {
-		return "", fmt.Errorf("no sub-groups with active keys for aggregate group '%s'", group.Name)
+		return "", fmt.Errorf("no sub-groups with active keys for model '%s' in aggregate group '%s'", model, group.Name)
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"aggregate_group": group.Name,
 		"selected_group":  selectedName,
+		"model":           model,
 	}).Debug("Selected sub-group from aggregate")
 
 	return selectedName, nil
@@ -117,6 +143,8 @@ func (m *SubGroupManager) createSelector(group *models.Group) *selector {
 			subGroupID:    sg.SubGroupID,
 			weight:        sg.Weight,
 			currentWeight: 0,
+			models:        utils.SplitAndTrim(sg.Models, ","),
+			priority:      sg.Priority,
 		})
 	}
 
@@ -125,24 +153,29 @@ func (m *SubGroupManager) createSelector(group *models.Group) *selector {
 	}
 
 	return &selector{
-		groupID:   group.ID,
-		groupName: group.Name,
-		subGroups: items,
-		store:     m.store,
+		groupID:        group.ID,
+		groupName:      group.Name,
+		mode:           group.EffectiveConfig.AggregateSelectionMode,
+		subGroups:      items,
+		store:          m.store,
+		channelFactory: m.channelFactory,
 	}
 }
 
-// selector encapsulates the weighted round-robin algorithm for a single aggregate group
+// selector encapsulates the sub-group selection algorithms for a single aggregate group
 type selector struct {
-	groupID   uint
-	groupName string
-	subGroups []subGroupItem
-	store     store.Store
-	mu        sync.Mutex
+	groupID        uint
+	groupName      string
+	mode           string
+	subGroups      []subGroupItem
+	store          store.Store
+	channelFactory *channel.Factory
+	mu             sync.Mutex
 }
 
-// selectNext uses weighted round-robin algorithm to select a sub-group with active keys
-func (s *selector) selectNext() string {
+// selectNext uses weighted round-robin algorithm to select a sub-group with active keys that serves the model.
+// Skipping the other sub-groups in the round-robin sequence keeps the weights among those that serve it.
+func (s *selector) selectNext(model string, exclude map[uint]bool) string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -151,6 +184,9 @@ func (s *selector) selectNext() string {
 	}
 
 	if len(s.subGroups) == 1 {
+		if exclude[s.subGroups[0].subGroupID] || !s.subGroups[0].serves(model) {
+			return ""
+		}
 		if s.hasActiveKeys(s.subGroups[0].subGroupID) {
 			return s.subGroups[0].name
 		}
@@ -161,16 +197,15 @@ func (s *selector) selectNext() string {
 		return ""
 	}
 
-	attempted := make(map[uint]bool)
-	for len(attempted) < len(s.subGroups) {
-		item := s.selectByWeight()
-		if item == nil {
-			break
-		}
+	if s.mode == aggregateModePriority {
+		return s.selectByPriority(model, exclude)
+	}
 
-		if attempted[item.subGroupID] {
-			continue
-		}
+	attempted := make(map[uint]bool)
+	eligible := func(item *subGroupItem) bool {
+		return !attempted[item.subGroupID] && !exclude[item.subGroupID] && item.serves(model)
+	}
+	for item := s.selectByWeight(eligible); item != nil; item = s.selectByWeight(eligible) {
 		attempted[item.subGroupID] = true
 
 		if s.hasActiveKeys(item.subGroupID) {
@@ -197,13 +232,69 @@ func (s *selector) selectNext() string {
 	return ""
 }
 
-// selectByWeight implements smooth weighted round-robin algorithm
-func (s *selector) selectByWeight() *subGroupItem {
+// selectByPriority selects a sub-group serving the model with active keys and a healthy upstream, trying the
+// lowest priority value first. Sub-groups of the same priority share traffic by weight. Every request starts
+// again from the lowest value, so traffic falls back to a sub-group as soon as it recovers. A sub-group whose
+// active keys are all cooling down or saturated can still be selected; the proxy then fails over to the next one.
+func (s *selector) selectByPriority(model string, exclude map[uint]bool) string {
+	var priorities []int
+	for _, item := range s.subGroups {
+		if !exclude[item.subGroupID] && item.serves(model) {
+			priorities = append(priorities, item.priority)
+		}
+	}
+	if len(priorities) == 0 {
+		return ""
+	}
+	slices.Sort(priorities)
+	priorities = slices.Compact(priorities)
+
+	attempted := make(map[uint]bool)
+	for _, priority := range priorities {
+		eligible := func(item *subGroupItem) bool {
+			return item.priority == priority && !attempted[item.subGroupID] && !exclude[item.subGroupID] && item.serves(model)
+		}
+		for item := s.selectByWeight(eligible); item != nil; item = s.selectByWeight(eligible) {
+			attempted[item.subGroupID] = true
+
+			if s.hasActiveKeys(item.subGroupID) && s.hasHealthyUpstream(item.subGroupID) {
+				logrus.WithFields(logrus.Fields{
+					"aggregate_group": s.groupName,
+					"selected_group":  item.name,
+					"priority":        priority,
+					"attempts":        len(attempted),
+				}).Debug("Selected sub-group by priority")
+				return item.name
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"group_id":   item.subGroupID,
+				"group_name": item.name,
+				"priority":   priority,
+				"attempts":   len(attempted),
+			}).Debug("Sub-group has no active keys or healthy upstreams, trying next")
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"aggregate_group":  s.groupName,
+		"total_sub_groups": len(s.subGroups),
+	}).Warn("No sub-groups with active keys and healthy upstreams available")
+
+	return ""
+}
+
+// selectByWeight implements smooth weighted round-robin algorithm among the eligible sub-groups, or all of them
+// when eligible is nil. It returns nil when no sub-group is eligible.
+func (s *selector) selectByWeight(eligible func(item *subGroupItem) bool) *subGroupItem {
 	totalWeight := 0
 	var best *subGroupItem
 
 	for i := range s.subGroups {
 		item := &s.subGroups[i]
+		if eligible != nil && !eligible(item) {
+			continue
+		}
 		totalWeight += item.weight
 		item.currentWeight += item.weight
 
@@ -213,7 +304,7 @@ func (s *selector) selectByWeight() *subGroupItem {
 	}
 
 	if best == nil {
-		return &s.subGroups[0]
+		return nil
 	}
 
 	best.currentWeight -= totalWeight
@@ -233,3 +324,8 @@ func (s *selector) hasActiveKeys(groupID uint) bool {
 	}
 	return length > 0
 }
+
+// hasHealthyUpstream checks if a sub-group has an upstream whose circuit breaker lets requests through
+func (s *selector) hasHealthyUpstream(groupID uint) bool {
+	return s.channelFactory == nil || s.channelFactory.HasHealthyUpstream(groupID)
+}
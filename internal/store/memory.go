@@ -217,6 +217,26 @@ func (s *MemoryStore) HIncrBy(key, field string, incr int64) (int64, error) {
 	return newVal, nil
 }
 
+func (s *MemoryStore) HDel(key string, fields ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rawHash, exists := s.data[key]
+	if !exists {
+		return nil
+	}
+
+	hash, ok := rawHash.(map[string]string)
+	if !ok {
+		return fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+
+	for _, field := range fields {
+		delete(hash, field)
+	}
+	return nil
+}
+
 // --- LIST operations ---
 
 func (s *MemoryStore) LPush(key string, values ...any) error {
@@ -320,6 +340,26 @@ func (s *MemoryStore) LLen(key string) (int64, error) {
 	return int64(len(list)), nil
 }
 
+// LRange returns all elements of a list.
+func (s *MemoryStore) LRange(key string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rawList, exists := s.data[key]
+	if !exists {
+		return []string{}, nil
+	}
+
+	list, ok := rawList.([]string)
+	if !ok {
+		return nil, fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+
+	result := make([]string, len(list))
+	copy(result, list)
+	return result, nil
+}
+
 // --- SET operations ---
 
 // SAdd adds members to a set.
@@ -377,6 +417,84 @@ func (s *MemoryStore) SPopN(key string, count int64) ([]string, error) {
 	return popped, nil
 }
 
+// --- SORTED SET operations ---
+
+// sortedSet returns the sorted set stored at the key, creating it when create is set. The caller holds s.mu.
+func (s *MemoryStore) sortedSet(key string, create bool) (map[string]float64, error) {
+	rawSet, exists := s.data[key]
+	if !exists {
+		if !create {
+			return nil, nil
+		}
+		set := make(map[string]float64)
+		s.data[key] = set
+		return set, nil
+	}
+
+	set, ok := rawSet.(map[string]float64)
+	if !ok {
+		return nil, fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+	return set, nil
+}
+
+// ZAdd adds a member to a sorted set, or updates its score.
+func (s *MemoryStore) ZAdd(key string, score float64, member string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set, err := s.sortedSet(key, true)
+	if err != nil {
+		return err
+	}
+	set[member] = score
+	return nil
+}
+
+// ZRem removes members from a sorted set.
+func (s *MemoryStore) ZRem(key string, members ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set, err := s.sortedSet(key, false)
+	if err != nil || set == nil {
+		return err
+	}
+	for _, member := range members {
+		delete(set, member)
+	}
+	return nil
+}
+
+// ZRemRangeByScore removes the members of a sorted set with a score between min and max, inclusive.
+func (s *MemoryStore) ZRemRangeByScore(key string, min, max float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set, err := s.sortedSet(key, false)
+	if err != nil || set == nil {
+		return err
+	}
+	for member, score := range set {
+		if score >= min && score <= max {
+			delete(set, member)
+		}
+	}
+	return nil
+}
+
+// ZCard returns the number of members of a sorted set.
+func (s *MemoryStore) ZCard(key string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set, err := s.sortedSet(key, false)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(set)), nil
+}
+
 // --- Pub/Sub operations ---
 
 // memorySubscription implements the Subscription interface for the in-memory store.
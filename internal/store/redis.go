@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"sync"
 	"time"
 
@@ -100,6 +102,10 @@ func (s *RedisStore) HIncrBy(key, field string, incr int64) (int64, error) {
 	return s.client.HIncrBy(context.Background(), s.prefixKey(key), field, incr).Result()
 }
 
+func (s *RedisStore) HDel(key string, fields ...string) error {
+	return s.client.HDel(context.Background(), s.prefixKey(key), fields...).Err()
+}
+
 // --- LIST operations ---
 
 func (s *RedisStore) LPush(key string, values ...any) error {
@@ -127,6 +133,11 @@ func (s *RedisStore) LLen(key string) (int64, error) {
 	return s.client.LLen(context.Background(), s.prefixKey(key)).Result()
 }
 
+// LRange returns all elements of a list.
+func (s *RedisStore) LRange(key string) ([]string, error) {
+	return s.client.LRange(context.Background(), s.prefixKey(key), 0, -1).Result()
+}
+
 // --- SET operations ---
 
 func (s *RedisStore) SAdd(key string, members ...any) error {
@@ -137,6 +148,40 @@ func (s *RedisStore) SPopN(key string, count int64) ([]string, error) {
 	return s.client.SPopN(context.Background(), s.prefixKey(key), count).Result()
 }
 
+// --- SORTED SET operations ---
+
+func (s *RedisStore) ZAdd(key string, score float64, member string) error {
+	return s.client.ZAdd(context.Background(), s.prefixKey(key), redis.Z{Score: score, Member: member}).Err()
+}
+
+func (s *RedisStore) ZRem(key string, members ...string) error {
+	args := make([]any, len(members))
+	for i, member := range members {
+		args[i] = member
+	}
+	return s.client.ZRem(context.Background(), s.prefixKey(key), args...).Err()
+}
+
+func (s *RedisStore) ZRemRangeByScore(key string, min, max float64) error {
+	return s.client.ZRemRangeByScore(context.Background(), s.prefixKey(key), formatScore(min), formatScore(max)).Err()
+}
+
+func (s *RedisStore) ZCard(key string) (int64, error) {
+	return s.client.ZCard(context.Background(), s.prefixKey(key)).Result()
+}
+
+// formatScore formats a score bound for Redis, which spells infinite bounds as -inf and +inf.
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, -1):
+		return "-inf"
+	case math.IsInf(score, 1):
+		return "+inf"
+	default:
+		return strconv.FormatFloat(score, 'f', -1, 64)
+	}
+}
+
 // --- Pipeliner implementation ---
 
 type redisPipeliner struct {
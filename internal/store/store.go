@@ -44,17 +44,25 @@ type Store interface {
 	HSet(key string, values map[string]any) error
 	HGetAll(key string) (map[string]string, error)
 	HIncrBy(key, field string, incr int64) (int64, error)
+	HDel(key string, fields ...string) error
 
 	// LIST operations
 	LPush(key string, values ...any) error
 	LRem(key string, count int64, value any) error
 	Rotate(key string) (string, error)
 	LLen(key string) (int64, error)
+	LRange(key string) ([]string, error)
 
 	// SET operations
 	SAdd(key string, members ...any) error
 	SPopN(key string, count int64) ([]string, error)
 
+	// SORTED SET operations
+	ZAdd(key string, score float64, member string) error
+	ZRem(key string, members ...string) error
+	ZRemRangeByScore(key string, min, max float64) error
+	ZCard(key string) (int64, error)
+
 	// Close closes the store and releases any underlying resources.
 	Close() error
 
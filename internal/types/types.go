@@ -26,21 +26,44 @@ type SystemSettings struct {
 	EnableRequestBodyLogging       bool   `json:"enable_request_body_logging" default:"false" name:"config.enable_request_body_logging" category:"config.category.basic" desc:"config.enable_request_body_logging_desc"`
 
 	// 请求设置
-	RequestTimeout        int    `json:"request_timeout" default:"600" name:"config.request_timeout" category:"config.category.request" desc:"config.request_timeout_desc" validate:"required,min=1"`
-	ConnectTimeout        int    `json:"connect_timeout" default:"15" name:"config.connect_timeout" category:"config.category.request" desc:"config.connect_timeout_desc" validate:"required,min=1"`
-	IdleConnTimeout       int    `json:"idle_conn_timeout" default:"120" name:"config.idle_conn_timeout" category:"config.category.request" desc:"config.idle_conn_timeout_desc" validate:"required,min=1"`
-	ResponseHeaderTimeout int    `json:"response_header_timeout" default:"600" name:"config.response_header_timeout" category:"config.category.request" desc:"config.response_header_timeout_desc" validate:"required,min=1"`
-	MaxIdleConns          int    `json:"max_idle_conns" default:"100" name:"config.max_idle_conns" category:"config.category.request" desc:"config.max_idle_conns_desc" validate:"required,min=1"`
-	MaxIdleConnsPerHost   int    `json:"max_idle_conns_per_host" default:"50" name:"config.max_idle_conns_per_host" category:"config.category.request" desc:"config.max_idle_conns_per_host_desc" validate:"required,min=1"`
-	ProxyURL              string `json:"proxy_url" name:"config.proxy_url" category:"config.category.request" desc:"config.proxy_url_desc"`
+	RequestTimeout              int    `json:"request_timeout" default:"600" name:"config.request_timeout" category:"config.category.request" desc:"config.request_timeout_desc" validate:"required,min=1"`
+	ConnectTimeout              int    `json:"connect_timeout" default:"15" name:"config.connect_timeout" category:"config.category.request" desc:"config.connect_timeout_desc" validate:"required,min=1"`
+	IdleConnTimeout             int    `json:"idle_conn_timeout" default:"120" name:"config.idle_conn_timeout" category:"config.category.request" desc:"config.idle_conn_timeout_desc" validate:"required,min=1"`
+	ResponseHeaderTimeout       int    `json:"response_header_timeout" default:"600" name:"config.response_header_timeout" category:"config.category.request" desc:"config.response_header_timeout_desc" validate:"required,min=1"`
+	StreamFirstChunkTimeout     int    `json:"stream_first_chunk_timeout" default:"60" name:"config.stream_first_chunk_timeout" category:"config.category.request" desc:"config.stream_first_chunk_timeout_desc" validate:"required,min=1"`
+	HedgeDelayMs                int    `json:"hedge_delay_ms" default:"0" name:"config.hedge_delay_ms" category:"config.category.request" desc:"config.hedge_delay_ms_desc" validate:"required,min=0"`
+	HedgeBudgetPercent          int    `json:"hedge_budget_percent" default:"10" name:"config.hedge_budget_percent" category:"config.category.request" desc:"config.hedge_budget_percent_desc" validate:"required,min=0,max=100"`
+	CircuitBreakerThreshold     int    `json:"circuit_breaker_threshold" default:"5" name:"config.circuit_breaker_threshold" category:"config.category.request" desc:"config.circuit_breaker_threshold_desc" validate:"required,min=0"`
+	CircuitBreakerCooldown      int    `json:"circuit_breaker_cooldown" default:"30" name:"config.circuit_breaker_cooldown" category:"config.category.request" desc:"config.circuit_breaker_cooldown_desc" validate:"required,min=1"`
+	UpstreamHealthCheckInterval int    `json:"upstream_health_check_interval" default:"0" name:"config.upstream_health_check_interval" category:"config.category.request" desc:"config.upstream_health_check_interval_desc" validate:"required,min=0"`
+	UpstreamSelectionStrategy   string `json:"upstream_selection_strategy" default:"weighted" name:"config.upstream_selection_strategy" category:"config.category.request" desc:"config.upstream_selection_strategy_desc" validate:"required,oneof=weighted latency"`
+	UpstreamExplorationPercent  int    `json:"upstream_exploration_percent" default:"10" name:"config.upstream_exploration_percent" category:"config.category.request" desc:"config.upstream_exploration_percent_desc" validate:"required,min=0,max=100"`
+	MaxIdleConns                int    `json:"max_idle_conns" default:"100" name:"config.max_idle_conns" category:"config.category.request" desc:"config.max_idle_conns_desc" validate:"required,min=1"`
+	MaxIdleConnsPerHost         int    `json:"max_idle_conns_per_host" default:"50" name:"config.max_idle_conns_per_host" category:"config.category.request" desc:"config.max_idle_conns_per_host_desc" validate:"required,min=1"`
+	ProxyURL                    string `json:"proxy_url" name:"config.proxy_url" category:"config.category.request" desc:"config.proxy_url_desc"`
+	EnableProtocolTranslation   bool   `json:"enable_protocol_translation" default:"false" name:"config.enable_protocol_translation" category:"config.category.request" desc:"config.enable_protocol_translation_desc"`
 
 	// 密钥配置
 	MaxRetries                   int    `json:"max_retries" default:"3" name:"config.max_retries" category:"config.category.key" desc:"config.max_retries_desc" validate:"required,min=0"`
+	RetryStatusCodes             string `json:"retry_status_codes" default:"4xx,5xx" name:"config.retry_status_codes" category:"config.category.key" desc:"config.retry_status_codes_desc" validate:"status_codes"`
+	NeverRetryStatusCodes        string `json:"never_retry_status_codes" default:"404" name:"config.never_retry_status_codes" category:"config.category.key" desc:"config.never_retry_status_codes_desc" validate:"status_codes"`
+	RetryErrorClasses            string `json:"retry_error_classes" default:"connection,timeout,stream" name:"config.retry_error_classes" category:"config.category.key" desc:"config.retry_error_classes_desc" validate:"error_classes"`
+	RetryBackoffBaseMs           int    `json:"retry_backoff_base_ms" default:"0" name:"config.retry_backoff_base" category:"config.category.key" desc:"config.retry_backoff_base_desc" validate:"required,min=0"`
+	RetryBackoffMaxMs            int    `json:"retry_backoff_max_ms" default:"10000" name:"config.retry_backoff_max" category:"config.category.key" desc:"config.retry_backoff_max_desc" validate:"required,min=0"`
+	RetryTotalTimeout            int    `json:"retry_total_timeout" default:"0" name:"config.retry_total_timeout" category:"config.category.key" desc:"config.retry_total_timeout_desc" validate:"required,min=0"`
+	AggregateMaxAttempts         int    `json:"aggregate_max_attempts" default:"0" name:"config.aggregate_max_attempts" category:"config.category.key" desc:"config.aggregate_max_attempts_desc" validate:"required,min=0"`
+	AggregateSelectionMode       string `json:"aggregate_selection_mode" default:"weighted" name:"config.aggregate_selection_mode" category:"config.category.key" desc:"config.aggregate_selection_mode_desc" validate:"required,oneof=weighted priority"`
 	BlacklistThreshold           int    `json:"blacklist_threshold" default:"3" name:"config.blacklist_threshold" category:"config.category.key" desc:"config.blacklist_threshold_desc" validate:"required,min=0"`
+	ModelCooldownSeconds         int    `json:"model_cooldown_seconds" default:"60" name:"config.model_cooldown_seconds" category:"config.category.key" desc:"config.model_cooldown_seconds_desc" validate:"required,min=0"`
+	KeySelectionStrategy         string `json:"key_selection_strategy" default:"round_robin" name:"config.key_selection_strategy" category:"config.category.key" desc:"config.key_selection_strategy_desc" validate:"required,oneof=round_robin random lru least_inflight weighted"`
+	MaxConcurrencyPerKey         int    `json:"max_concurrency_per_key" default:"0" name:"config.max_concurrency_per_key" category:"config.category.key" desc:"config.max_concurrency_per_key_desc" validate:"required,min=0"`
+	KeyConcurrencyWaitMs         int    `json:"key_concurrency_wait_ms" default:"1000" name:"config.key_concurrency_wait_ms" category:"config.category.key" desc:"config.key_concurrency_wait_ms_desc" validate:"required,min=0"`
 	KeyValidationIntervalMinutes int    `json:"key_validation_interval_minutes" default:"60" name:"config.key_validation_interval" category:"config.category.key" desc:"config.key_validation_interval_desc" validate:"required,min=1"`
 	KeyValidationConcurrency     int    `json:"key_validation_concurrency" default:"10" name:"config.key_validation_concurrency" category:"config.category.key" desc:"config.key_validation_concurrency_desc" validate:"required,min=1"`
 	KeyValidationTimeoutSeconds  int    `json:"key_validation_timeout_seconds" default:"20" name:"config.key_validation_timeout" category:"config.category.key" desc:"config.key_validation_timeout_desc" validate:"required,min=1"`
 	PremiumModels                string `json:"premium_models" default:"" name:"config.premium_models" category:"config.category.key" desc:"config.premium_models_desc"`
+	PremiumOrgKeysFirst          bool   `json:"premium_org_keys_first" default:"false" name:"config.premium_org_keys_first" category:"config.category.key" desc:"config.premium_org_keys_first_desc"`
+	PremiumExplorationPercent    int    `json:"premium_exploration_percent" default:"5" name:"config.premium_exploration_percent" category:"config.category.key" desc:"config.premium_exploration_percent_desc" validate:"required,min=0,max=100"`
 
 	// For cache
 	ProxyKeysMap     map[string]struct{} `json:"-"`
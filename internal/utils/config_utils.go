@@ -33,7 +33,7 @@ func GenerateSettingsMetadata(s *types.SystemSettings) []models.SystemSettingInf
 		validateTag := field.Tag.Get("validate")
 		categoryTag := field.Tag.Get("category")
 
-		var minValue *int
+		var minValue, maxValue *int
 		var required bool
 
 		rules := strings.Split(validateTag, ",")
@@ -46,6 +46,11 @@ func GenerateSettingsMetadata(s *types.SystemSettings) []models.SystemSettingInf
 				if val, err := strconv.Atoi(valStr); err == nil {
 					minValue = &val
 				}
+			} else if strings.HasPrefix(rule, "max=") {
+				valStr := strings.TrimPrefix(rule, "max=")
+				if val, err := strconv.Atoi(valStr); err == nil {
+					maxValue = &val
+				}
 			}
 		}
 
@@ -58,6 +63,7 @@ func GenerateSettingsMetadata(s *types.SystemSettings) []models.SystemSettingInf
 			Description:  descTag,
 			Category:     categoryTag,
 			MinValue:     minValue,
+			MaxValue:     maxValue,
 			Required:     required,
 		}
 		settingsInfo = append(settingsInfo, info)
@@ -179,7 +185,7 @@ func GetValidationEndpoint(group *models.Group) string {
 
 	// Return default validation endpoint based on channel type
 	switch group.ChannelType {
-	case "openai":
+	case "openai", "azure":
 		return "/v1/chat/completions"
 	case "anthropic":
 		return "/v1/messages"
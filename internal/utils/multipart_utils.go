@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// maxMultipartFieldSize bounds the text fields read from a multipart body; file parts are skipped, not copied.
+const maxMultipartFieldSize = 1 << 20
+
+// MultipartFile describes a file part of a multipart body.
+type MultipartFile struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"`
+}
+
+// MultipartSummary describes a multipart body without its file contents.
+type MultipartSummary struct {
+	Fields map[string]string `json:"fields"`
+	Files  []MultipartFile   `json:"files"`
+}
+
+// MultipartBoundary returns the boundary of a multipart/form-data content type.
+func MultipartBoundary(contentType string) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return "", false
+	}
+	return params["boundary"], true
+}
+
+// IsMultipartForm reports whether the content type is multipart/form-data.
+func IsMultipartForm(contentType string) bool {
+	_, ok := MultipartBoundary(contentType)
+	return ok
+}
+
+// GetMultipartField returns the value of a text field of a multipart body.
+func GetMultipartField(body []byte, contentType, name string) (string, bool) {
+	boundary, ok := MultipartBoundary(contentType)
+	if !ok {
+		return "", false
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextRawPart()
+		if err != nil {
+			return "", false
+		}
+		if part.FileName() == "" && part.FormName() == name {
+			value, err := io.ReadAll(io.LimitReader(part, maxMultipartFieldSize))
+			if err != nil {
+				return "", false
+			}
+			return string(value), true
+		}
+	}
+}
+
+// SetMultipartFields rewrites or appends text fields of a multipart body.
+// Other parts, including files, are copied byte for byte and the boundary is kept,
+// so the request's Content-Type header stays valid.
+func SetMultipartFields(body []byte, contentType string, fields map[string]string) ([]byte, error) {
+	boundary, ok := MultipartBoundary(contentType)
+	if !ok {
+		return nil, fmt.Errorf("not a multipart/form-data body")
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	var buf bytes.Buffer
+	buf.Grow(len(body))
+	writer := multipart.NewWriter(&buf)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return nil, err
+	}
+
+	written := make(map[string]bool, len(fields))
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read multipart body: %w", err)
+		}
+
+		name := part.FormName()
+		if value, found := fields[name]; found && part.FileName() == "" {
+			if !written[name] {
+				if err := writeMultipartField(writer, part.Header, value); err != nil {
+					return nil, err
+				}
+				written[name] = true
+			}
+			continue
+		}
+
+		dst, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(dst, part); err != nil {
+			return nil, fmt.Errorf("failed to copy multipart part: %w", err)
+		}
+	}
+
+	for name, value := range fields {
+		if !written[name] {
+			if err := writer.WriteField(name, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeMultipartField writes a text field, keeping the original part headers.
+func writeMultipartField(writer *multipart.Writer, header textproto.MIMEHeader, value string) error {
+	dst, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(dst, value)
+	return err
+}
+
+// MultipartFieldValue formats a parameter override as a multipart text field.
+func MultipartFieldValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case map[string]any, []any:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// SummarizeMultipart lists the text fields and file metadata of a multipart body.
+func SummarizeMultipart(body []byte, contentType string) (*MultipartSummary, error) {
+	boundary, ok := MultipartBoundary(contentType)
+	if !ok {
+		return nil, fmt.Errorf("not a multipart/form-data body")
+	}
+
+	summary := &MultipartSummary{Fields: make(map[string]string)}
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			return summary, nil
+		}
+		if err != nil {
+			return summary, fmt.Errorf("failed to read multipart body: %w", err)
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxMultipartFieldSize))
+			if err != nil {
+				return summary, err
+			}
+			summary.Fields[part.FormName()] = string(value)
+			continue
+		}
+
+		size, err := io.Copy(io.Discard, part)
+		if err != nil {
+			return summary, err
+		}
+		summary.Files = append(summary.Files, MultipartFile{
+			Field:       part.FormName(),
+			Filename:    part.FileName(),
+			ContentType: strings.TrimSpace(part.Header.Get("Content-Type")),
+			Size:        size,
+		})
+	}
+}
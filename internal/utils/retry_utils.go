@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Error classes of failures that carry no upstream status code.
+const (
+	ErrorClassConnection = "connection" // The upstream could not be reached or dropped the connection
+	ErrorClassTimeout    = "timeout"    // The upstream did not answer in time
+	ErrorClassStream     = "stream"     // A stream failed before its first chunk was sent to the client
+)
+
+// StatusCodeRange is an inclusive range of HTTP status codes.
+type StatusCodeRange struct {
+	From int
+	To   int
+}
+
+// StatusCodeSet is a set of HTTP status codes built from ranges.
+type StatusCodeSet []StatusCodeRange
+
+// Contains reports whether the status code is in the set.
+func (s StatusCodeSet) Contains(code int) bool {
+	for _, r := range s {
+		if code >= r.From && code <= r.To {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseStatusCodeSet parses a comma-separated list of status codes ("429"),
+// ranges ("500-599") and classes ("5xx").
+func ParseStatusCodeSet(spec string) (StatusCodeSet, error) {
+	var set StatusCodeSet
+	for _, part := range SplitAndTrim(spec, ",") {
+		r, err := parseStatusCodeRange(strings.ToLower(part))
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, r)
+	}
+	return set, nil
+}
+
+func parseStatusCodeRange(part string) (StatusCodeRange, error) {
+	if len(part) == 3 && strings.HasSuffix(part, "xx") && part[0] >= '1' && part[0] <= '5' {
+		class := int(part[0]-'0') * 100
+		return StatusCodeRange{From: class, To: class + 99}, nil
+	}
+
+	from, to, isRange := strings.Cut(part, "-")
+	if !isRange {
+		to = from
+	}
+	fromCode, err := parseStatusCode(from)
+	if err != nil {
+		return StatusCodeRange{}, err
+	}
+	toCode, err := parseStatusCode(to)
+	if err != nil {
+		return StatusCodeRange{}, err
+	}
+	if fromCode > toCode {
+		return StatusCodeRange{}, fmt.Errorf("invalid status code range: %s", part)
+	}
+	return StatusCodeRange{From: fromCode, To: toCode}, nil
+}
+
+func parseStatusCode(s string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || code < 100 || code > 599 {
+		return 0, fmt.Errorf("invalid status code: %s", s)
+	}
+	return code, nil
+}
+
+// ParseErrorClasses parses a comma-separated list of error classes.
+func ParseErrorClasses(spec string) (map[string]bool, error) {
+	classes := make(map[string]bool)
+	for _, part := range SplitAndTrim(spec, ",") {
+		class := strings.ToLower(part)
+		switch class {
+		case ErrorClassConnection, ErrorClassTimeout, ErrorClassStream:
+			classes[class] = true
+		default:
+			return nil, fmt.Errorf("unknown error class: %s", part)
+		}
+	}
+	return classes, nil
+}
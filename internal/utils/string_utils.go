@@ -41,6 +41,27 @@ func SplitAndTrim(s string, sep string) []string {
 	return result
 }
 
+// MatchWildcard reports whether s matches the pattern, in which '*' matches any run of characters.
+func MatchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
+
 // StringToSet converts a separator-delimited string into a set
 func StringToSet(s string, sep string) map[string]struct{} {
 	parts := SplitAndTrim(s, sep)
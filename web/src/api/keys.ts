@@ -1,12 +1,14 @@
 import i18n from "@/locales";
 import type {
   APIKey,
+  CapabilityMatrix,
   Group,
   GroupConfigOption,
   GroupStatsResponse,
   KeyStatus,
   ParentAggregateGroup,
   TaskInfo,
+  TierKeyCount,
 } from "@/types/models";
 import http from "@/utils/http";
 
@@ -81,6 +83,7 @@ export const keysApi = {
       total_items: number;
       total_pages: number;
     };
+    tier_counts: TierKeyCount[];
   }> {
     const res = await http.get("/keys", { params });
     return res.data;
@@ -117,6 +120,12 @@ export const keysApi = {
     return res.data;
   },
 
+  // 获取分组密钥的模型能力矩阵
+  async getKeyCapabilities(group_id: number): Promise<CapabilityMatrix> {
+    const res = await http.get("/keys/capabilities", { params: { group_id } });
+    return res.data;
+  },
+
   // 更新密钥备注
   async updateKeyNotes(keyId: number, notes: string): Promise<void> {
     await http.put(`/keys/${keyId}/notes`, { notes }, { hideMessage: true });
@@ -288,6 +297,28 @@ export const keysApi = {
     });
   },
 
+  // 更新子分组服务的模型
+  async updateSubGroupModels(
+    aggregateGroupId: number,
+    subGroupId: number,
+    models: string
+  ): Promise<void> {
+    await http.put(`/groups/${aggregateGroupId}/sub-groups/${subGroupId}/models`, {
+      models,
+    });
+  },
+
+  // 更新子分组优先级
+  async updateSubGroupPriority(
+    aggregateGroupId: number,
+    subGroupId: number,
+    priority: number
+  ): Promise<void> {
+    await http.put(`/groups/${aggregateGroupId}/sub-groups/${subGroupId}/priority`, {
+      priority,
+    });
+  },
+
   // 删除子分组
   async deleteSubGroup(aggregateGroupId: number, subGroupId: number): Promise<void> {
     await http.delete(`/groups/${aggregateGroupId}/sub-groups/${subGroupId}`);
@@ -226,6 +226,10 @@ export default {
     validateAllKeys: "Validate All Keys",
     validateValidKeys: "Validate Valid Keys",
     validateInvalidKeys: "Validate Invalid Keys",
+    modelCapabilities: "Model Capabilities",
+    modelCapabilitiesOf: "Model capabilities of {group}",
+    modelCapabilitiesHint: "Learned from requests: ✓ the key served the model, ✗ the upstream refused it. Empty cells were never tried.",
+    noModelCapabilities: "No capabilities learned yet",
     keyCopied: "Key copied to clipboard",
     copyFailed: "Copy failed",
     testingKey: "Testing key...",
@@ -261,6 +265,9 @@ export default {
     basicInfo: "Basic Information",
     displayName: "Display Name",
     channelType: "Channel Type",
+    aggregateSelectionMode: "Selection Mode",
+    selectionModeWeighted: "Weighted",
+    selectionModePriority: "Priority",
     sortOrder: "Sort Order",
     testModel: "Test Model",
     testPath: "Test Path",
@@ -438,6 +445,9 @@ export default {
     restore: "Restore",
     requestsShort: "RQ",
     failuresShort: "FL",
+    backupTier: "Backup tier {tier}",
+    coolingDownUntil: "Rate limited until {time}",
+    modelCoolingDownUntil: "{model} rate limited until {time}",
     testShort: "Go",
     restoreShort: "↻",
     validShort: "OK",
@@ -477,6 +487,14 @@ export default {
     statusActive: "Active",
     statusDisabled: "Disabled",
     statusUnavailable: "Unavailable",
+    models: "Models",
+    allModels: "All models",
+    modelsPlaceholder: "e.g. gpt-4o, claude-*",
+    modelsTooltip:
+      "Comma-separated model names this sub group serves. * matches any characters. Leave empty to serve all models.",
+    priority: "Priority",
+    priorityTooltip:
+      "In priority mode, sub groups with lower values are used first; higher values take traffic only while those are unavailable.",
   },
   logs: {
     title: "Logs",
@@ -490,6 +508,7 @@ export default {
     copyFailed: "Failed to copy {type}",
     retryRequest: "Retry Request",
     finalRequest: "Final Request",
+    hedgeRequest: "Hedge Request",
     time: "Time",
     requestType: "Request Type",
     responseType: "Response Type",
@@ -551,6 +570,7 @@ export default {
     loadFailed: "Failed to load settings",
     pleaseInput: "Please input {field}",
     minValueError: "Value cannot be less than {value}",
+    maxValueError: "Value cannot be greater than {value}",
     inputNumber: "Please input number",
     inputContent: "Please input content",
     saveSettings: "Save Settings",
@@ -225,6 +225,10 @@ export default {
     validateAllKeys: "すべてのキーを検証",
     validateValidKeys: "有効なキーを検証",
     validateInvalidKeys: "無効なキーを検証",
+    modelCapabilities: "モデル対応状況",
+    modelCapabilitiesOf: "{group} のモデル対応状況",
+    modelCapabilitiesHint: "リクエストから学習します：✓ はキーでモデルを利用できたこと、✗ は上流に拒否されたことを示します。空欄は未使用です。",
+    noModelCapabilities: "学習済みの対応状況はまだありません",
     keyCopied: "キーがクリップボードにコピーされました",
     copyFailed: "コピーに失敗しました",
     testingKey: "キーをテスト中...",
@@ -260,6 +264,9 @@ export default {
     basicInfo: "基本情報",
     displayName: "表示名",
     channelType: "チャンネルタイプ",
+    aggregateSelectionMode: "選択モード",
+    selectionModeWeighted: "重み付け",
+    selectionModePriority: "優先度",
     sortOrder: "並び順",
     testModel: "テストモデル",
     testPath: "テストパス",
@@ -438,6 +445,9 @@ export default {
     restore: "復元",
     requestsShort: "要求",
     failuresShort: "失敗",
+    backupTier: "予備ティア {tier}",
+    coolingDownUntil: "{time} までレート制限中",
+    modelCoolingDownUntil: "{model} は {time} までレート制限中",
     testShort: "試験",
     restoreShort: "復元",
     validShort: "有効",
@@ -478,6 +488,14 @@ export default {
     statusActive: "有効",
     statusDisabled: "無効",
     statusUnavailable: "利用不可",
+    models: "モデル",
+    allModels: "すべてのモデル",
+    modelsPlaceholder: "例: gpt-4o, claude-*",
+    modelsTooltip:
+      "このサブグループが扱うモデル名（カンマ区切り）。* は任意の文字に一致します。空欄ですべてのモデルを扱います。",
+    priority: "優先度",
+    priorityTooltip:
+      "優先度モードでは値の小さいサブグループから使われ、それらが使えない間だけ値の大きいサブグループに流れます。",
   },
   logs: {
     title: "ログ",
@@ -491,6 +509,7 @@ export default {
     copyFailed: "{type}のコピーに失敗しました",
     retryRequest: "リトライリクエスト",
     finalRequest: "最終リクエスト",
+    hedgeRequest: "ヘッジリクエスト",
     time: "時間",
     requestType: "リクエストタイプ",
     responseType: "レスポンスタイプ",
@@ -552,6 +571,7 @@ export default {
     loadFailed: "設定の読み込みに失敗しました",
     pleaseInput: "{field}を入力してください",
     minValueError: "値は{value}より小さくできません",
+    maxValueError: "値は{value}より大きくできません",
     inputNumber: "数値を入力してください",
     inputContent: "内容を入力してください",
     saveSettings: "設定を保存",
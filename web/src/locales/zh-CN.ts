@@ -224,6 +224,10 @@ export default {
     validateAllKeys: "验证所有密钥",
     validateValidKeys: "验证有效密钥",
     validateInvalidKeys: "验证无效密钥",
+    modelCapabilities: "模型能力",
+    modelCapabilitiesOf: "{group} 的模型能力",
+    modelCapabilitiesHint: "根据请求自动学习：✓ 表示密钥成功调用过该模型，✗ 表示上游拒绝访问，空白表示尚未尝试。",
+    noModelCapabilities: "尚未学习到模型能力",
     keyCopied: "密钥已复制到剪贴板",
     copyFailed: "复制失败",
     testingKey: "正在测试密钥...",
@@ -257,6 +261,9 @@ export default {
     basicInfo: "基础信息",
     displayName: "显示名称",
     channelType: "渠道类型",
+    aggregateSelectionMode: "选择模式",
+    selectionModeWeighted: "按权重",
+    selectionModePriority: "按优先级",
     sortOrder: "排序",
     testModel: "测试模型",
     testPath: "测试路径",
@@ -422,6 +429,9 @@ export default {
     restore: "恢复",
     requestsShort: "请求",
     failuresShort: "失败",
+    backupTier: "备用层级 {tier}",
+    coolingDownUntil: "限流冷却至 {time}",
+    modelCoolingDownUntil: "{model} 限流冷却至 {time}",
     testShort: "测试",
     restoreShort: "恢复",
     validShort: "有效",
@@ -461,6 +471,12 @@ export default {
     statusActive: "有效",
     statusDisabled: "禁用",
     statusUnavailable: "无效",
+    models: "模型",
+    allModels: "全部模型",
+    modelsPlaceholder: "例如 gpt-4o, claude-*",
+    modelsTooltip: "该子分组服务的模型名，用逗号分隔，* 匹配任意字符。留空表示服务全部模型。",
+    priority: "优先级",
+    priorityTooltip: "优先级模式下数值越小越先使用，只有它们不可用时才使用数值更大的子分组。",
   },
   logs: {
     title: "日志",
@@ -474,6 +490,7 @@ export default {
     copyFailed: "复制{type}失败",
     retryRequest: "重试请求",
     finalRequest: "最终请求",
+    hedgeRequest: "对冲请求",
     time: "时间",
     requestType: "请求类型",
     responseType: "响应类型",
@@ -535,6 +552,7 @@ export default {
     loadFailed: "获取设置失败",
     pleaseInput: "请输入{field}",
     minValueError: "值不能小于{value}",
+    maxValueError: "值不能大于{value}",
     inputNumber: "请输入数值",
     inputContent: "请输入内容",
     saveSettings: "保存设置",
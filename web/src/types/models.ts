@@ -23,12 +23,16 @@ export interface APIKey {
   status: KeyStatus;
   request_count: number;
   failure_count: number;
+  weight: number;
+  tier: number;
   is_organization_key: boolean;
   organization_id?: string;
   organization_name?: string;
   last_used_at?: string;
   created_at: string;
   updated_at: string;
+  cooldown_until?: string;
+  model_cooldowns?: Record<string, string>;
 }
 
 export interface UpstreamInfo {
@@ -52,6 +56,8 @@ export interface SubGroupConfig {
 export interface SubGroupInfo {
   group: Group;
   weight: number;
+  models: string; // 逗号分隔的模型名或通配符，空表示全部模型
+  priority: number; // 优先级模式下数值越小越优先
   total_keys: number;
   active_keys: number;
   invalid_keys: number;
@@ -95,6 +101,8 @@ export interface GroupConfigOption {
   name: string;
   description: string;
   default_value: string | number;
+  min_value?: number;
+  max_value?: number;
 }
 
 // GroupStatsResponse defines the complete statistics for a group.
@@ -110,6 +118,36 @@ export interface KeyStats {
   total_keys: number;
   active_keys: number;
   invalid_keys: number;
+  tier_counts?: TierKeyCount[];
+}
+
+// TierKeyCount defines the key counts of one priority tier in a group.
+export interface TierKeyCount {
+  tier: number;
+  total_keys: number;
+  active_keys: number;
+}
+
+// ModelCapabilitySummary counts the keys learned to support or refuse a model.
+export interface ModelCapabilitySummary {
+  model: string;
+  supported_keys: number;
+  unsupported_keys: number;
+}
+
+// KeyCapabilities holds the learned model capabilities of one key.
+export interface KeyCapabilities {
+  key_id: number;
+  key_value: string;
+  status: KeyStatus;
+  tier: number;
+  models: Record<string, boolean>;
+}
+
+// CapabilityMatrix defines the model capability matrix of a group's keys.
+export interface CapabilityMatrix {
+  models: ModelCapabilitySummary[];
+  keys: KeyCapabilities[];
 }
 
 // RequestStats defines the statistics for requests over a period.
@@ -162,7 +200,8 @@ export interface RequestLog {
   duration_ms: number;
   error_message: string;
   user_agent: string;
-  request_type: "retry" | "final";
+  request_type: "retry" | "final" | "hedge";
+  request_id?: string;
   group_name?: string;
   parent_group_name?: string;
   key_value?: string;
@@ -197,7 +236,8 @@ export interface LogFilter {
   error_contains?: string;
   start_time?: string | null;
   end_time?: string | null;
-  request_type?: "retry" | "final";
+  request_type?: "retry" | "final" | "hedge";
+  request_id?: string;
 }
 
 export interface DashboardStats {